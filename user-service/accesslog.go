@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+)
+
+// AccessLog writes a line of JSON for every request.
+type AccessLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewAccessLog returns a log writing to w.
+func NewAccessLog(w io.Writer) *AccessLog {
+	return &AccessLog{w: w}
+}
+
+type accessLogEntry struct {
+	Time          time.Time `json:"time"`
+	RequestID     string    `json:"requestId,omitempty"`
+	RemoteAddr    string    `json:"remoteAddr"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Route         string    `json:"route,omitempty"`
+	Status        int       `json:"status"`
+	Bytes         int       `json:"bytes"`
+	Duration      float64   `json:"duration"`
+	Principal     string    `json:"principal,omitempty"`
+	Authorization string    `json:"authorization,omitempty"`
+	UserAgent     string    `json:"userAgent,omitempty"`
+}
+
+// Filter is a container filter logging the requests. The Authorization
+// header is logged without its credentials.
+func (l *AccessLog) Filter(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	start := time.Now()
+	next(req, resp)
+
+	e := accessLogEntry{
+		Time:          start.UTC(),
+		RequestID:     RequestIDOf(req),
+		RemoteAddr:    req.Request.RemoteAddr,
+		Method:        req.Request.Method,
+		Path:          req.Request.URL.Path,
+		Route:         selectedRoutePath(req),
+		Status:        resp.StatusCode(),
+		Bytes:         resp.ContentLength(),
+		Duration:      time.Since(start).Seconds(),
+		Authorization: redactAuthorization(req.Request.Header.Get("Authorization")),
+		UserAgent:     req.Request.UserAgent(),
+	}
+	if e.Status == 0 {
+		e.Status = http.StatusOK
+	}
+	if claims := ClaimsOf(req); claims != nil {
+		e.Principal = claims.Subject
+	}
+	l.write(e)
+}
+
+func (l *AccessLog) write(e accessLogEntry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Access log: %v", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		log.Printf("Access log: %v", err)
+	}
+}
+
+// redactAuthorization keeps only the scheme of an Authorization header.
+func redactAuthorization(h string) string {
+	if h == "" {
+		return ""
+	}
+	scheme := strings.SplitN(h, " ", 2)[0]
+	return scheme + " [REDACTED]"
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactAuthorization(t *testing.T) {
+	for h, want := range map[string]string{
+		"":                       "",
+		"Bearer eyJhbGciOi.x.y":  "Bearer [REDACTED]",
+		"Basic YWRtaW46YWRtaW4=": "Basic [REDACTED]",
+		"secret":                 "secret [REDACTED]",
+	} {
+		if got := redactAuthorization(h); got != want {
+			t.Errorf("redactAuthorization(%q) = %q, want %q", h, got, want)
+		}
+	}
+}
+
+func TestAccessLogWrite(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewAccessLog(&buf)
+	l.write(accessLogEntry{Time: time.Now(), RequestID: "abc", Method: "GET", Path: "/users", Status: 200})
+	l.write(accessLogEntry{Time: time.Now(), Method: "POST", Path: "/login", Status: 401})
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var e accessLogEntry
+	if err := json.Unmarshal(lines[0], &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.RequestID != "abc" || e.Status != 200 || e.Path != "/users" {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestAccessLogFilter(t *testing.T) {
+	var buf bytes.Buffer
+	c := newContract(t)
+	c.container.Filter(NewAccessLog(&buf).Filter)
+	tokens, err := c.auth.issueTokens("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := bearer(tokens.Token)
+	header.Set("X-Request-ID", "abc")
+	rec := c.call("POST", "/users", header, `{"name": "john", "age": 30}`, http.StatusCreated)
+	if id := rec.Header().Get("X-Request-ID"); id != "abc" {
+		t.Errorf("X-Request-ID = %q, want the one of the request", id)
+	}
+	rec = c.call("POST", "/login", http.Header{}, `{"name": "admin", "password": "wrong"}`, http.StatusUnprocessableEntity)
+	generated := rec.Header().Get("X-Request-ID")
+	if generated == "" {
+		t.Error("no X-Request-ID generated")
+	}
+
+	out := buf.String()
+	if strings.Contains(out, tokens.Token) || strings.Contains(out, "wrong") {
+		t.Errorf("the log shows credentials:\n%s", out)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), out)
+	}
+	var created, login accessLogEntry
+	if err := json.Unmarshal([]byte(lines[0]), &created); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &login); err != nil {
+		t.Fatal(err)
+	}
+	if created.RequestID != "abc" || created.Principal != "admin" || created.Status != http.StatusCreated ||
+		created.Route != "/users/" || created.Authorization != "Bearer [REDACTED]" {
+		t.Errorf("entry = %+v", created)
+	}
+	if login.RequestID != generated || login.Principal != "" || login.Status != http.StatusUnprocessableEntity {
+		t.Errorf("entry = %+v", login)
+	}
+}
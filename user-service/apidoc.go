@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/spec"
+	"github.com/tangblue/wiki/user-service/validate"
+)
+
+// KeyResponseHeaders is the route metadata documenting the headers of
+// responses, which restful.RouteBuilder.Returns cannot express.
+const KeyResponseHeaders = "response.headers"
+
+// ResponseHeaders maps a status code to header names and descriptions.
+type ResponseHeaders map[int]map[string]string
+
+// commonResponseHeaders are the headers of the responses of any route.
+var commonResponseHeaders = ResponseHeaders{
+	http.StatusTooManyRequests: {"Retry-After": "seconds to wait before retrying"},
+}
+
+// addResponseHeaders adds the KeyResponseHeaders metadata of the routes,
+// and commonResponseHeaders, to the responses of the operations of swo.
+func addResponseHeaders(swo *spec.Swagger, wss []*restful.WebService) {
+	if swo.Paths == nil {
+		return
+	}
+	for _, ws := range wss {
+		for _, route := range ws.Routes() {
+			headers, _ := route.Metadata[KeyResponseHeaders].(ResponseHeaders)
+			op := routeOperation(swo, route)
+			if op == nil || op.Responses == nil {
+				continue
+			}
+			for _, headers := range []ResponseHeaders{headers, commonResponseHeaders} {
+				for code, each := range headers {
+					resp, ok := op.Responses.StatusCodeResponses[code]
+					if !ok {
+						continue
+					}
+					for name, desc := range each {
+						resp.AddHeader(name, spec.ResponseHeader().Typed("string", "").WithDescription(desc))
+					}
+					op.Responses.StatusCodeResponses[code] = resp
+				}
+			}
+		}
+	}
+}
+
+// addBodySchemas refers the body parameters of the operations of swo to
+// the definitions of the models the routes read. restful.RouteBuilder.Reads
+// passes the type name to restful.Parameter.DataType, which takes it for a
+// sample string, so the parameters come out as strings without a schema.
+func addBodySchemas(swo *spec.Swagger, wss []*restful.WebService) {
+	if swo.Paths == nil {
+		return
+	}
+	for _, ws := range wss {
+		for _, route := range ws.Routes() {
+			op := routeOperation(swo, route)
+			if op == nil || route.ReadSample == nil {
+				continue
+			}
+			schema := spec.RefSchema("#/definitions/" + definitionName(reflect.TypeOf(route.ReadSample)))
+			if t := reflect.TypeOf(route.ReadSample); t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+				schema = spec.ArrayProperty(spec.RefSchema("#/definitions/" + definitionName(t.Elem())))
+			}
+			for i, p := range op.Parameters {
+				if p.In == "body" && p.Schema == nil {
+					p.Schema = schema
+					p.Type, p.Format = "", ""
+					op.Parameters[i] = p
+				}
+			}
+		}
+	}
+}
+
+// definitionName returns the name of the definition of t.
+func definitionName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
+// addValidationConstraints documents the constraints package validate
+// checks in the definitions of the models the routes read.
+func addValidationConstraints(swo *spec.Swagger, wss []*restful.WebService) {
+	for _, ws := range wss {
+		for _, route := range ws.Routes() {
+			if route.ReadSample == nil {
+				continue
+			}
+			name := reflect.TypeOf(route.ReadSample).String()
+			if s, ok := swo.Definitions[name]; ok {
+				validate.Schema(&s, route.ReadSample)
+				swo.Definitions[name] = s
+			}
+		}
+	}
+}
+
+// sortParameterEnums sorts the enums of the parameters of swo, which
+// restful.Parameter.AllowableValues keeps in a map, so that the document
+// is the same on every build.
+func sortParameterEnums(swo *spec.Swagger) {
+	if swo.Paths == nil {
+		return
+	}
+	for _, item := range swo.Paths.Paths {
+		for _, op := range []*spec.Operation{item.Get, item.Put, item.Post, item.Delete, item.Patch, item.Head, item.Options} {
+			if op == nil {
+				continue
+			}
+			for _, p := range op.Parameters {
+				sort.Slice(p.Enum, func(i, j int) bool {
+					return fmt.Sprint(p.Enum[i]) < fmt.Sprint(p.Enum[j])
+				})
+			}
+		}
+	}
+}
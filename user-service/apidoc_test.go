@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestAddResponseHeaders(t *testing.T) {
+	doc := newContract(t).doc
+	for _, c := range []struct {
+		method, path, status string
+		headers              []string
+	}{
+		{"get", "/users", "200", []string{"X-Total-Count", "Link"}},
+		{"post", "/users", "201", []string{"ETag", "Location"}},
+		{"put", "/users", "201", []string{"ETag", "Location"}},
+		{"get", "/users/{userID}", "200", []string{"ETag"}},
+	} {
+		op := doc.Paths[c.path][c.method]
+		if op == nil || op.Responses[c.status] == nil {
+			t.Errorf("%s %s has no %s response", c.method, c.path, c.status)
+			continue
+		}
+		for _, name := range c.headers {
+			if op.Responses[c.status].Headers[name] == nil {
+				t.Errorf("%s %s %s response lacks header %s", c.method, c.path, c.status, name)
+			}
+		}
+	}
+}
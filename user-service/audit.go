@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restfulspec"
+	"github.com/tangblue/goapi/spec"
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+// Actions of the audit log.
+const (
+	AuditLogin          = "login"
+	AuditLoginFailed    = "login.failed"
+	AuditLockout        = "login.lockout"
+	AuditTokenRefresh   = "token.refresh"
+	AuditLogout         = "logout"
+	AuditRegister       = "register"
+	AuditPasswordChange = "password.change"
+	AuditUserCreate     = "user.create"
+	AuditUserUpdate     = "user.update"
+	AuditUserDelete     = "user.delete"
+)
+
+// AuditEntry is an event of the audit log. Hash is the SHA-256 of the
+// entry with an empty Hash, and Prev is the Hash of the entry before, so
+// that changing or removing an entry breaks the chain after it.
+type AuditEntry struct {
+	Seq       uint64                 `json:"seq" description:"sequence number of the entry"`
+	Time      time.Time              `json:"time" description:"time of the event"`
+	Actor     string                 `json:"actor" description:"subject of the caller, or the user name tried"`
+	Action    string                 `json:"action" description:"what happened"`
+	Target    string                 `json:"target,omitempty" description:"what it happened to"`
+	RequestID string                 `json:"requestId,omitempty" description:"X-Request-ID of the request"`
+	Diff      map[string]AuditChange `json:"diff,omitempty" description:"changed fields by JSON name"`
+	Prev      string                 `json:"prev" description:"hash of the previous entry"`
+	Hash      string                 `json:"hash" description:"hash of this entry"`
+}
+
+// AuditChange is the JSON of a field before and after an event. Either is
+// missing if the field did not exist.
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty" description:"value before"`
+	After  json.RawMessage `json:"after,omitempty" description:"value after"`
+}
+
+// addAuditChangeSchema documents the values of AuditChange as any JSON,
+// which the json.RawMessage fields would otherwise be documented as
+// strings.
+func addAuditChangeSchema(swo *spec.Swagger) {
+	s, ok := swo.Definitions["main.AuditChange"]
+	if !ok {
+		return
+	}
+	for name, prop := range s.Properties {
+		s.Properties[name] = spec.Schema{SchemaProps: spec.SchemaProps{Description: prop.Description}}
+	}
+	swo.Definitions["main.AuditChange"] = s
+}
+
+func (e AuditEntry) hash() (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// defaultAuditWindow is the number of recent entries an AuditLog keeps in
+// memory.
+const defaultAuditWindow = 10000
+
+// AuditLog is an append-only, hash chained log of events. When opened
+// from a file, every entry is appended to it as a line of JSON. Only the
+// last window entries are kept in memory; queries for older ones read the
+// file, and a log without a file forgets them.
+type AuditLog struct {
+	mu      sync.RWMutex
+	f       *os.File
+	entries []AuditEntry
+	window  int
+
+	qpFrom  *restful.Parameter
+	qpTo    *restful.Parameter
+	qpActor *restful.Parameter
+}
+
+func NewAuditLog() *AuditLog {
+	return &AuditLog{
+		window: defaultAuditWindow,
+		qpFrom: restful.QueryParameter("from", "earliest time of the events, RFC 3339").
+			DataType("string").
+			DataFormat("date-time"),
+		qpTo: restful.QueryParameter("to", "time before which the events happened, RFC 3339").
+			DataType("string").
+			DataFormat("date-time"),
+		qpActor: restful.QueryParameter("actor", "subject that caused the events").
+			DataType("string"),
+	}
+}
+
+// OpenAuditLog reads the log at path and verifies its chain before
+// appending to it.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := NewAuditLog()
+	l.f = f
+	if err := l.replay(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return l, nil
+}
+
+func (l *AuditLog) replay() error {
+	r := bufio.NewReader(l.f)
+	var prev AuditEntry
+	var end int64 // offset after the last complete entry
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// A crash cut the last entry short. Its write never
+				// succeeded, so drop it.
+				log.Printf("%s: dropping the partial entry at offset %d", l.f.Name(), end)
+				if err := l.f.Truncate(end); err != nil {
+					return err
+				}
+			}
+			break
+		} else if err != nil {
+			return err
+		}
+		e := AuditEntry{}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("audit entry at offset %d: %v", end, err)
+		}
+		end += int64(len(line))
+		if err := verifyAuditEntry(e, prev); err != nil {
+			return err
+		}
+		l.keep(e)
+		prev = e
+	}
+	_, err := l.f.Seek(0, io.SeekEnd)
+	return err
+}
+
+// verifyAuditEntry returns an error if e is not chained to prev, the entry
+// before it, which is zero for the first entry.
+func verifyAuditEntry(e, prev AuditEntry) error {
+	if e.Seq != prev.Seq+1 {
+		return fmt.Errorf("audit entry %d: sequence number is %d", prev.Seq+1, e.Seq)
+	}
+	if e.Prev != prev.Hash {
+		return fmt.Errorf("audit entry %d: not chained to entry %d", e.Seq, prev.Seq)
+	}
+	hash, err := e.hash()
+	if err != nil {
+		return err
+	}
+	if e.Hash != hash {
+		return fmt.Errorf("audit entry %d: hash mismatch", e.Seq)
+	}
+	return nil
+}
+
+// keep adds e to the entries in memory, dropping those that fell out of
+// the window. The caller must hold l.mu.
+func (l *AuditLog) keep(e AuditEntry) {
+	l.entries = append(l.entries, e)
+	// Copy the window once it has doubled, so that the dropped entries
+	// are freed.
+	if n := len(l.entries); n >= 2*l.window {
+		l.entries = append([]AuditEntry(nil), l.entries[n-l.window:]...)
+	}
+}
+
+// Append chains e to the log and returns it as logged.
+func (l *AuditLog) Append(e AuditEntry) (AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq, e.Prev = 1, ""
+	if n := len(l.entries); n > 0 {
+		e.Seq, e.Prev = l.entries[n-1].Seq+1, l.entries[n-1].Hash
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	hash, err := e.hash()
+	if err != nil {
+		return e, err
+	}
+	e.Hash = hash
+
+	if l.f != nil {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return e, err
+		}
+		end, err := l.f.Seek(0, io.SeekEnd)
+		if err != nil {
+			return e, err
+		}
+		if _, err := l.f.Write(append(b, '\n')); err != nil {
+			// Drop what was written, or the next entry would follow
+			// a partial one.
+			l.f.Truncate(end)
+			return e, err
+		}
+		if err := l.f.Sync(); err != nil {
+			return e, err
+		}
+	}
+	l.keep(e)
+	return e, nil
+}
+
+// Query returns the entries from from until to of actor. A zero from or
+// to, or an empty actor, does not limit the entries.
+func (l *AuditLog) Query(from, to time.Time, actor string) ([]AuditEntry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	match := func(e AuditEntry) bool {
+		return (from.IsZero() || !e.Time.Before(from)) &&
+			(to.IsZero() || e.Time.Before(to)) &&
+			(actor == "" || e.Actor == actor)
+	}
+	entries := []AuditEntry{}
+	if len(l.entries) > 0 && l.entries[0].Seq > 1 && l.f != nil &&
+		(from.IsZero() || from.Before(l.entries[0].Time)) {
+		var err error
+		if entries, err = l.queryFile(l.entries[0].Seq, match); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range l.entries {
+		if match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// queryFile returns the entries before seq in the file that match. The
+// caller must hold l.mu.
+func (l *AuditLog) queryFile(seq uint64, match func(AuditEntry) bool) ([]AuditEntry, error) {
+	f, err := os.Open(l.f.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []AuditEntry{}
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		e := AuditEntry{}
+		if err := dec.Decode(&e); err != nil {
+			return nil, err
+		}
+		if e.Seq >= seq {
+			return entries, nil
+		}
+		if match(e) {
+			entries = append(entries, e)
+		}
+	}
+}
+
+func (l *AuditLog) Close() error {
+	if l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// Record logs an action of the caller of req on target. The actor is the
+// subject of the claims of req unless given. before and after are the
+// states of target, either of which may be nil. A failure is only logged,
+// as the action has happened anyway. Nothing is recorded to a nil log.
+func (l *AuditLog) Record(req *restful.Request, actor, action, target string, before, after interface{}) {
+	if l == nil {
+		return
+	}
+	if actor == "" {
+		if claims := ClaimsOf(req); claims != nil {
+			actor = claims.Subject
+		}
+	}
+	diff, err := auditDiff(before, after)
+	if err == nil {
+		_, err = l.Append(AuditEntry{
+			Actor:     actor,
+			Action:    action,
+			Target:    target,
+			RequestID: RequestIDOf(req),
+			Diff:      diff,
+		})
+	}
+	if err != nil {
+		log.Printf("Audit %s of %s by %s: %v", action, target, actor, err)
+	}
+}
+
+// auditDiff returns the members that differ between the JSON objects of
+// before and after.
+func auditDiff(before, after interface{}) (map[string]AuditChange, error) {
+	members := func(v interface{}) (map[string]json.RawMessage, error) {
+		m := map[string]json.RawMessage{}
+		if v == nil {
+			return m, nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return m, json.Unmarshal(b, &m)
+	}
+	old, err := members(before)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := members(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]AuditChange{}
+	for name, v := range old {
+		if !bytes.Equal(v, cur[name]) {
+			diff[name] = AuditChange{Before: v, After: cur[name]}
+		}
+	}
+	for name, v := range cur {
+		if _, ok := old[name]; !ok {
+			diff[name] = AuditChange{After: v}
+		}
+	}
+	if len(diff) == 0 {
+		return nil, nil
+	}
+	return diff, nil
+}
+
+// WebService lets admins query the log. Callers authenticate by client
+// certificates if auth has client principals, and by tokens otherwise.
+func (l *AuditLog) WebService(path string, tags []string, auth *Auth) *restful.WebService {
+	authenticate := auth.jwtAuth
+	if len(auth.clientPrincipals) > 0 {
+		authenticate = auth.clientCertAuth
+	}
+
+	ws := new(restful.WebService)
+	ws.Path(path).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("").Doc("query the audit log").
+		Handler(l.findEntries).
+		Operation("queryAuditLog").
+		Do(problem.Declare).
+		Param(l.qpFrom).
+		Param(l.qpTo).
+		Param(l.qpActor).
+		Returns(http.StatusOK, "OK", []AuditEntry{}).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Do(authenticate, auth.requireScopes(ScopeAuditRead), validated, traced))
+
+	return ws
+}
+
+func (l *AuditLog) findEntries(req *restful.Request, resp *restful.Response) {
+	var times [2]time.Time
+	for i, p := range []*restful.Parameter{l.qpFrom, l.qpTo} {
+		name := p.Data().Name
+		s := req.QueryParameter(name)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			problem.Write(req, resp, problem.New(http.StatusBadRequest, "Query parameter is invalid.").
+				WithField(name, "expected an RFC 3339 time"))
+			return
+		}
+		times[i] = t
+	}
+	entries, err := l.Query(times[0], times[1], req.QueryParameter(l.qpActor.Data().Name))
+	if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	resp.WriteEntity(entries)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	john := User{ID: 1, Name: "john", Age: 21}
+	diff, err := auditDiff(john, User{ID: 1, Name: "john", Age: 22})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 1 || string(diff["age"].Before) != "21" || string(diff["age"].After) != "22" {
+		t.Fatalf("auditDiff = %v, want the age", diff)
+	}
+	for _, e := range []AuditEntry{
+		{Actor: "admin", Action: AuditLogin},
+		{Actor: "admin", Action: AuditUserUpdate, Target: userTarget(1), Diff: diff},
+		{Actor: "john", Action: AuditLogin},
+	} {
+		if _, err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := l.Query(start, time.Time{}, "admin"); err != nil || len(got) != 2 || got[1].Action != AuditUserUpdate {
+		t.Errorf("Query of admin = %v, %v", got, err)
+	}
+	if got, err := l.Query(time.Time{}, start, ""); err != nil || len(got) != 0 {
+		t.Errorf("Query before start = %v, %v; want none", got, err)
+	}
+	l.Close()
+
+	l, err = OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got, err := l.Query(time.Time{}, time.Time{}, ""); err != nil || len(got) != 3 {
+		t.Fatalf("reopened log has %d entries, %v; want 3", len(got), err)
+	}
+	l.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(b), `"actor":"john"`, `"actor":"jane"`, 1)
+	if err := ioutil.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenAuditLog(path); err == nil {
+		t.Error("opened a tampered log")
+	}
+}
+
+func TestAuditLogWindow(t *testing.T) {
+	l, err := OpenAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.window = 2
+	for _, actor := range []string{"admin", "john", "admin", "john", "admin"} {
+		if _, err := l.Append(AuditEntry{Actor: actor, Action: AuditLogin}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(l.entries) >= 2*l.window {
+		t.Errorf("%d entries in memory, want fewer than %d", len(l.entries), 2*l.window)
+	}
+	got, err := l.Query(time.Time{}, time.Time{}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seqs []uint64
+	for _, e := range got {
+		seqs = append(seqs, e.Seq)
+	}
+	if !reflect.DeepEqual(seqs, []uint64{1, 3, 5}) {
+		t.Errorf("Query of admin returned entries %v, want 1, 3 and 5", seqs)
+	}
+
+	mem := NewAuditLog()
+	mem.window = 2
+	for i := 0; i < 5; i++ {
+		if _, err := mem.Append(AuditEntry{Actor: "admin", Action: AuditLogin}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := mem.Query(time.Time{}, time.Time{}, ""); err != nil || len(got) >= 4 || got[len(got)-1].Seq != 5 {
+		t.Errorf("Query of a log without file = %v, %v; want the recent entries", got, err)
+	}
+}
+
+func TestAuditLogPartialEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(AuditEntry{Actor: "admin", Action: AuditLogin}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":2,"time":"20`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for i := 0; i < 2; i++ {
+		l, err := OpenAuditLog(path)
+		if err != nil {
+			t.Fatalf("open %d with a partial entry: %v", i+1, err)
+		}
+		e, err := l.Append(AuditEntry{Actor: "john", Action: AuditLogin})
+		if err != nil || e.Seq != uint64(i+2) {
+			t.Fatalf("Append = %v, %v; want sequence number %d", e, err, i+2)
+		}
+		l.Close()
+	}
+}
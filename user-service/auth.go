@@ -0,0 +1,360 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restfulspec"
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+type LoginInfo struct {
+	Name     string `json:"name" description:"user name" required:"true"`
+	Password string `json:"password" description:"password" required:"true"`
+}
+
+type PasswordChange struct {
+	Name        string `json:"name" description:"user name" required:"true"`
+	Password    string `json:"password" description:"current password" required:"true"`
+	NewPassword string `json:"newPassword" description:"new password" required:"true"`
+}
+
+// minPasswordLength and maxPasswordLength are enforced on registration and
+// password changes. bcrypt refuses passwords longer than 72 bytes.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// checkPassword returns the problem with the length of password, or nil.
+func checkPassword(password string) *problem.Problem {
+	if len(password) < minPasswordLength {
+		return problem.New(http.StatusBadRequest, "Password is too short.")
+	}
+	if len(password) > maxPasswordLength {
+		return problem.Newf(http.StatusBadRequest, "Password is longer than %d bytes.", maxPasswordLength)
+	}
+	return nil
+}
+
+type JWTToken struct {
+	Token        string `json:"token" description:"JWT token"`
+	RefreshToken string `json:"refreshToken" description:"JWT token to get a new token with"`
+	ExpiresIn    int64  `json:"expiresIn" description:"lifetime of token in seconds"`
+}
+
+type RefreshToken struct {
+	RefreshToken string `json:"refreshToken" description:"JWT refresh token"`
+}
+
+type Auth struct {
+	keys        *KeySet
+	credentials *Credentials
+	revocations *Revocations
+	audit       *AuditLog
+	ipLimiter   *RateLimiter
+	userLimiter *RateLimiter
+	lockout     *Lockout
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+	// clientPrincipals maps client certificates to accounts.
+	clientPrincipals ClientPrincipals
+
+	hpAuthorization *restful.Parameter
+}
+
+func NewAuth(keys *KeySet, credentials *Credentials, audit *AuditLog, accessTTL, refreshTTL time.Duration, limits AuthLimits) *Auth {
+	return &Auth{
+		keys:        keys,
+		credentials: credentials,
+		revocations: NewRevocations(),
+		audit:       audit,
+		ipLimiter:   NewRateLimiter(limits.IPRate, limits.IPBurst),
+		userLimiter: NewRateLimiter(limits.UserRate, limits.UserBurst),
+		lockout:     NewLockout(limits.LockoutThreshold, limits.LockoutBase, limits.LockoutMax),
+		accessTTL:   accessTTL,
+		refreshTTL:  refreshTTL,
+		hpAuthorization: restful.HeaderParameter("authorization", "JWT in authorization header").
+			Required(true).
+			LengthRange(8, 2048).
+			DefaultValue("Bearer "),
+	}
+}
+
+func (a *Auth) WebService(path string, tags []string) *restful.WebService {
+	ws := new(restful.WebService)
+	ws.Path(path).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.POST("").Doc("login").
+		Handler(a.createToken).
+		Operation("login").
+		Do(problem.Declare, a.rateLimited).
+		Reads(LoginInfo{}).
+		Returns(http.StatusOK, "OK", JWTToken{}).
+		Returns(http.StatusUnprocessableEntity, "Bad user name or password", problem.Problem{}).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Do(validated, traced))
+
+	ws.Route(ws.POST("/refresh").Doc("exchange a refresh token for new tokens").
+		Handler(a.refreshToken).
+		Do(problem.Declare, a.rateLimited).
+		Reads(RefreshToken{}).
+		Returns(http.StatusOK, "OK", JWTToken{}).
+		Returns(http.StatusUnauthorized, "Invalid refresh token", problem.Problem{}).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Do(validated, traced))
+
+	ws.Route(ws.POST("/logout").Doc("revoke the token and optionally a refresh token").
+		Handler(a.logout).
+		Do(problem.Declare).
+		Param(a.hpAuthorization).
+		Reads(RefreshToken{}).
+		Returns(http.StatusNoContent, "No Content", nil).
+		Returns(http.StatusUnauthorized, "Not Authorized", problem.Problem{}).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Do(validated, traced))
+
+	ws.Route(ws.POST("/register").Doc("register a user name and password").
+		Handler(a.register).
+		Do(problem.Declare, a.rateLimited).
+		Reads(LoginInfo{}).
+		Returns(http.StatusUnprocessableEntity, "Name or password is missing", problem.Problem{}).
+		Returns(http.StatusCreated, "Created", nil).
+		Returns(http.StatusBadRequest, "Password is too short", problem.Problem{}).
+		Returns(http.StatusConflict, "User name is taken", problem.Problem{}).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Do(validated, traced))
+
+	ws.Route(ws.PUT("/password").Doc("change password").
+		Handler(a.changePassword).
+		Do(problem.Declare, a.rateLimited).
+		Reads(PasswordChange{}).
+		Returns(http.StatusNoContent, "No Content", nil).
+		Returns(http.StatusBadRequest, "Password is too short", problem.Problem{}).
+		Returns(http.StatusUnprocessableEntity, "Bad user name or password", problem.Problem{}).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Do(validated, traced))
+
+	return ws
+}
+
+// JWKSWebService publishes the public keys that verify our tokens at
+// path/jwks.json, normally under "/.well-known".
+func (a *Auth) JWKSWebService(path string, tags []string) *restful.WebService {
+	ws := new(restful.WebService)
+	ws.Path(path).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/jwks.json").Doc("get the JSON Web Key Set").
+		Handler(a.findKeys).
+		Operation("getJWKS").
+		Do(problem.Declare).
+		Returns(http.StatusOK, "OK", JWKS{}).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Do(validated, traced))
+
+	return ws
+}
+
+func (a *Auth) basicAuthenticate(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	u, p, ok := req.Request.BasicAuth()
+	if ok && !a.throttle(req, resp, u) {
+		return
+	}
+	if !ok || !a.credentials.Verify(u, p) {
+		if ok {
+			a.failed(req, u)
+		}
+		resp.AddHeader("WWW-Authenticate", "Basic realm=Protected Area")
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Authentication is required."))
+		return
+	}
+	a.lockout.Reset(u)
+	roles := a.credentials.Roles(u)
+	req.SetAttribute(attrClaims, &Claims{
+		StandardClaims: jwt.StandardClaims{Subject: u},
+		Roles:          roles,
+		Scope:          scopesOf(roles),
+	})
+	next(req, resp)
+}
+
+// throttle writes 429 Too Many Requests and returns false if name may not
+// try to authenticate now.
+func (a *Auth) throttle(req *restful.Request, resp *restful.Response, name string) bool {
+	if ok, retry := a.userLimiter.Allow(name); !ok {
+		writeTooManyRequests(req, resp, retry, "Too many attempts for this user.")
+		return false
+	}
+	if d := a.lockout.Locked(name); d > 0 {
+		writeTooManyRequests(req, resp, d, "User is locked out after failed attempts.")
+		return false
+	}
+	return true
+}
+
+// failed counts a failed authentication of name, which may lock it out.
+func (a *Auth) failed(req *restful.Request, name string) {
+	a.audit.Record(req, name, AuditLoginFailed, name, nil, nil)
+	if a.lockout.Fail(name) > 0 {
+		a.audit.Record(req, name, AuditLockout, name, nil, nil)
+	}
+}
+
+func (a *Auth) createToken(req *restful.Request, resp *restful.Response) {
+	li := LoginInfo{}
+	if err := req.ReadEntity(&li); err != nil {
+		problem.Write(req, resp, problem.Decode(err))
+		return
+	}
+	if !a.throttle(req, resp, li.Name) {
+		return
+	}
+	if !a.credentials.Verify(li.Name, li.Password) {
+		a.failed(req, li.Name)
+		problem.Write(req, resp, problem.New(http.StatusUnprocessableEntity, "Bad user name or password."))
+		return
+	}
+	a.lockout.Reset(li.Name)
+	tokens, err := a.issueTokens(li.Name)
+	if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	a.audit.Record(req, li.Name, AuditLogin, li.Name, nil, nil)
+	resp.WriteEntity(tokens)
+}
+
+func (a *Auth) refreshToken(req *restful.Request, resp *restful.Response) {
+	rt := RefreshToken{}
+	if err := req.ReadEntity(&rt); err != nil {
+		problem.Write(req, resp, problem.Decode(err))
+		return
+	}
+	claims, err := a.parseToken(rt.RefreshToken, tokenTypeRefresh)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Authentication is required."))
+		return
+	}
+	// A refresh token is good for one use only, even if it is raced.
+	if !a.revocations.RevokeIfNew(claims.Id, time.Unix(claims.ExpiresAt, 0)) {
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Authentication is required."))
+		return
+	}
+
+	tokens, err := a.issueTokens(claims.Subject)
+	if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	a.audit.Record(req, claims.Subject, AuditTokenRefresh, claims.Subject, nil, nil)
+	resp.WriteEntity(tokens)
+}
+
+func (a *Auth) logout(req *restful.Request, resp *restful.Response) {
+	bt, err := a.bearerToken(req)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Authentication is required."))
+		return
+	}
+	claims, err := a.parseToken(bt, tokenTypeAccess)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Authentication is required."))
+		return
+	}
+
+	// The refresh token is optional, but must belong to the same subject.
+	rt := RefreshToken{}
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(&rt); err != nil {
+			problem.Write(req, resp, problem.Decode(err))
+			return
+		}
+	}
+	if rt.RefreshToken != "" {
+		rc, err := a.parseToken(rt.RefreshToken, tokenTypeRefresh)
+		if err == nil && rc.Subject == claims.Subject {
+			a.revocations.Revoke(rc.Id, time.Unix(rc.ExpiresAt, 0))
+		}
+	}
+
+	a.revocations.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0))
+	a.audit.Record(req, claims.Subject, AuditLogout, claims.Subject, nil, nil)
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Auth) register(req *restful.Request, resp *restful.Response) {
+	li := LoginInfo{}
+	if err := req.ReadEntity(&li); err != nil {
+		problem.Write(req, resp, problem.Decode(err))
+		return
+	}
+	if li.Name == "" {
+		problem.Write(req, resp, problem.New(http.StatusUnprocessableEntity, "User name is empty."))
+		return
+	}
+	if p := checkPassword(li.Password); p != nil {
+		problem.Write(req, resp, p)
+		return
+	}
+	if err := a.credentials.Add(li.Name, li.Password, RoleUser); err == ErrCredentialExists {
+		problem.Write(req, resp, problem.New(http.StatusConflict, "User name is taken."))
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	a.audit.Record(req, li.Name, AuditRegister, li.Name, nil, nil)
+	resp.WriteHeader(http.StatusCreated)
+}
+
+func (a *Auth) changePassword(req *restful.Request, resp *restful.Response) {
+	pc := PasswordChange{}
+	if err := req.ReadEntity(&pc); err != nil {
+		problem.Write(req, resp, problem.Decode(err))
+		return
+	}
+	if p := checkPassword(pc.NewPassword); p != nil {
+		problem.Write(req, resp, p)
+		return
+	}
+	if !a.throttle(req, resp, pc.Name) {
+		return
+	}
+	if err := a.credentials.Change(pc.Name, pc.Password, pc.NewPassword); err == ErrBadCredentials {
+		a.failed(req, pc.Name)
+		problem.Write(req, resp, problem.New(http.StatusUnprocessableEntity, "Bad user name or password."))
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	a.lockout.Reset(pc.Name)
+	a.audit.Record(req, pc.Name, AuditPasswordChange, pc.Name, nil, nil)
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Auth) findKeys(req *restful.Request, resp *restful.Response) {
+	resp.AddHeader("Cache-Control", "max-age=300")
+	resp.WriteEntity(a.keys.JWKS())
+}
+
+func (a *Auth) JWTAuthenticate(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	bt, err := a.bearerToken(req)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Authentication is required."))
+		return
+	}
+	claims, err := a.parseToken(bt, tokenTypeAccess)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Authentication is required."))
+		return
+	}
+	req.SetAttribute(attrClaims, claims)
+
+	next(req, resp)
+}
@@ -0,0 +1,882 @@
+// Code generated by clientgen from the OpenAPI document of UserService. DO NOT EDIT.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Client calls the API at BaseURL.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Token is the bearer token sent to the operations that require one.
+	Token string
+	// Username and Password authenticate the operations that require
+	// basic authentication.
+	Username, Password string
+}
+
+// New returns a client of the API at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// StatusError is a response with a status the operation does not declare,
+// or a body that does not match the declaration.
+type StatusError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+type request struct {
+	method, path string
+	query        url.Values
+	header       http.Header
+	contentType  string
+	body         interface{}
+	// auth is "bearer", "basic" or empty.
+	auth string
+	// errors makes the errors of the declared failure statuses.
+	errors map[int]func(http.Header, []byte) error
+	result interface{}
+}
+
+func (c *Client) do(ctx context.Context, r *request) error {
+	var body io.Reader
+	if r.contentType != "" {
+		b, err := json.Marshal(r.body)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	u := c.BaseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	req, err := http.NewRequest(r.method, u, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range r.header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	switch {
+	case r.auth == "bearer" && c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case r.auth == "basic" && c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if r.result == nil || len(b) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(b, r.result); err != nil {
+			return &StatusError{StatusCode: resp.StatusCode, Header: resp.Header, Body: b}
+		}
+		return nil
+	}
+	if newError, ok := r.errors[resp.StatusCode]; ok {
+		return newError(resp.Header, b)
+	}
+	return &StatusError{StatusCode: resp.StatusCode, Header: resp.Header, Body: b}
+}
+
+// addParam adds v to values under name unless v is a zero value. The
+// elements of a slice are added one by one if explode is set, else
+// joined by commas.
+func addParam(values map[string][]string, name string, v interface{}, explode bool) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.IsZero() {
+		return
+	}
+	var list []string
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			list = append(list, formatParam(rv.Index(i).Interface()))
+		}
+		if !explode {
+			list = []string{strings.Join(list, ",")}
+		}
+	} else {
+		list = []string{formatParam(v)}
+	}
+	values[name] = append(values[name], list...)
+}
+
+func formatParam(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+type AuditChange struct {
+	// value after
+	After interface{} `json:"after,omitempty"`
+	// value before
+	Before interface{} `json:"before,omitempty"`
+}
+
+type AuditEntry struct {
+	// what happened
+	Action string `json:"action"`
+	// subject of the caller, or the user name tried
+	Actor string `json:"actor"`
+	// changed fields by JSON name
+	Diff map[string]AuditChange `json:"diff,omitempty"`
+	// hash of this entry
+	Hash string `json:"hash"`
+	// hash of the previous entry
+	Prev string `json:"prev"`
+	// X-Request-ID of the request
+	RequestID string `json:"requestId,omitempty"`
+	// sequence number of the entry
+	Seq int `json:"seq"`
+	// what it happened to
+	Target string `json:"target,omitempty"`
+	// time of the event
+	Time time.Time `json:"time"`
+}
+
+type JWK struct {
+	// algorithm
+	Alg string `json:"alg"`
+	// curve
+	Crv string `json:"crv,omitempty"`
+	// RSA exponent
+	E string `json:"e,omitempty"`
+	// key ID
+	Kid string `json:"kid"`
+	// key type
+	Kty string `json:"kty"`
+	// RSA modulus
+	N string `json:"n,omitempty"`
+	// public key use
+	Use string `json:"use"`
+	// x coordinate or public key
+	X string `json:"x,omitempty"`
+	// y coordinate
+	Y string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	// keys that verify tokens
+	Keys []JWK `json:"keys"`
+}
+
+type JWTToken struct {
+	// lifetime of token in seconds
+	ExpiresIn int64 `json:"expiresIn"`
+	// JWT token to get a new token with
+	RefreshToken string `json:"refreshToken"`
+	// JWT token
+	Token string `json:"token"`
+}
+
+type LoginInfo struct {
+	// user name
+	Name string `json:"name"`
+	// password
+	Password string `json:"password"`
+}
+
+type PasswordChange struct {
+	// user name
+	Name string `json:"name"`
+	// new password
+	NewPassword string `json:"newPassword"`
+	// current password
+	Password string `json:"password"`
+}
+
+type RefreshToken struct {
+	// JWT refresh token
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+type User struct {
+	// age of the user
+	Age int32 `json:"age,omitempty"`
+	// identifier of the user
+	ID int64 `json:"id,omitempty"`
+	// name of the user
+	Name string `json:"name"`
+	// name of the account owning the user
+	Owner string `json:"owner,omitempty"`
+}
+
+type FieldError struct {
+	// what is wrong with the field
+	Detail string `json:"detail"`
+	// JSON name of the field
+	Field string `json:"field"`
+}
+
+type Problem struct {
+	// explanation of this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// problems of individual fields
+	Errors []FieldError `json:"errors,omitempty"`
+	// URI reference of this occurrence of the problem
+	Instance string `json:"instance,omitempty"`
+	// HTTP status code
+	Status int32 `json:"status"`
+	// short summary of the problem type
+	Title string `json:"title"`
+	// URI reference identifying the problem type
+	Type string `json:"type,omitempty"`
+}
+
+// NotModifiedError is a 304 Not Modified response.
+type NotModifiedError struct {
+	Header http.Header
+	Body   json.RawMessage
+}
+
+func (e *NotModifiedError) Error() string {
+	return "304 Not Modified"
+}
+
+func newNotModifiedError(header http.Header, body []byte) error {
+	e := &NotModifiedError{Header: header}
+	e.Body = body
+	return e
+}
+
+// BadRequestError is a 400 Bad Request response.
+type BadRequestError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *BadRequestError) Error() string {
+	if e.Body.Detail != "" {
+		return "400 Bad Request: " + e.Body.Detail
+	}
+	return "400 Bad Request"
+}
+
+func newBadRequestError(header http.Header, body []byte) error {
+	e := &BadRequestError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 400, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// UnauthorizedError is a 401 Unauthorized response.
+type UnauthorizedError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *UnauthorizedError) Error() string {
+	if e.Body.Detail != "" {
+		return "401 Unauthorized: " + e.Body.Detail
+	}
+	return "401 Unauthorized"
+}
+
+func newUnauthorizedError(header http.Header, body []byte) error {
+	e := &UnauthorizedError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 401, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// ForbiddenError is a 403 Forbidden response.
+type ForbiddenError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *ForbiddenError) Error() string {
+	if e.Body.Detail != "" {
+		return "403 Forbidden: " + e.Body.Detail
+	}
+	return "403 Forbidden"
+}
+
+func newForbiddenError(header http.Header, body []byte) error {
+	e := &ForbiddenError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 403, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// NotFoundError is a 404 Not Found response.
+type NotFoundError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Body.Detail != "" {
+		return "404 Not Found: " + e.Body.Detail
+	}
+	return "404 Not Found"
+}
+
+func newNotFoundError(header http.Header, body []byte) error {
+	e := &NotFoundError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 404, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// ConflictError is a 409 Conflict response.
+type ConflictError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *ConflictError) Error() string {
+	if e.Body.Detail != "" {
+		return "409 Conflict: " + e.Body.Detail
+	}
+	return "409 Conflict"
+}
+
+func newConflictError(header http.Header, body []byte) error {
+	e := &ConflictError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 409, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// PreconditionFailedError is a 412 Precondition Failed response.
+type PreconditionFailedError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *PreconditionFailedError) Error() string {
+	if e.Body.Detail != "" {
+		return "412 Precondition Failed: " + e.Body.Detail
+	}
+	return "412 Precondition Failed"
+}
+
+func newPreconditionFailedError(header http.Header, body []byte) error {
+	e := &PreconditionFailedError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 412, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// UnsupportedMediaTypeError is a 415 Unsupported Media Type response.
+type UnsupportedMediaTypeError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	if e.Body.Detail != "" {
+		return "415 Unsupported Media Type: " + e.Body.Detail
+	}
+	return "415 Unsupported Media Type"
+}
+
+func newUnsupportedMediaTypeError(header http.Header, body []byte) error {
+	e := &UnsupportedMediaTypeError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 415, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// UnprocessableEntityError is a 422 Unprocessable Entity response.
+type UnprocessableEntityError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *UnprocessableEntityError) Error() string {
+	if e.Body.Detail != "" {
+		return "422 Unprocessable Entity: " + e.Body.Detail
+	}
+	return "422 Unprocessable Entity"
+}
+
+func newUnprocessableEntityError(header http.Header, body []byte) error {
+	e := &UnprocessableEntityError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 422, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// TooManyRequestsError is a 429 Too Many Requests response.
+type TooManyRequestsError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *TooManyRequestsError) Error() string {
+	if e.Body.Detail != "" {
+		return "429 Too Many Requests: " + e.Body.Detail
+	}
+	return "429 Too Many Requests"
+}
+
+func newTooManyRequestsError(header http.Header, body []byte) error {
+	e := &TooManyRequestsError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 429, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// InternalServerErrorError is a 500 Internal Server Error response.
+type InternalServerErrorError struct {
+	Header http.Header
+	Body   Problem
+}
+
+func (e *InternalServerErrorError) Error() string {
+	if e.Body.Detail != "" {
+		return "500 Internal Server Error: " + e.Body.Detail
+	}
+	return "500 Internal Server Error"
+}
+
+func newInternalServerErrorError(header http.Header, body []byte) error {
+	e := &InternalServerErrorError{Header: header}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Body); err != nil {
+			return &StatusError{StatusCode: 500, Header: header, Body: body}
+		}
+	}
+	return e
+}
+
+// ChangePassword calls PUT /login/password to change password.
+//
+// Declared failures are returned as *BadRequestError, *UnprocessableEntityError, *TooManyRequestsError, *InternalServerErrorError.
+func (c *Client) ChangePassword(ctx context.Context, body *PasswordChange) error {
+	r := &request{method: "PUT", path: "/login/password", query: url.Values{}, header: http.Header{}}
+	r.contentType, r.body = "application/json", body
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		422: newUnprocessableEntityError,
+		429: newTooManyRequestsError,
+		500: newInternalServerErrorError,
+	}
+	return c.do(ctx, r)
+}
+
+// CreateUser calls POST /users to create a user with a new ID.
+//
+// The ID of the user in the body is ignored.
+//
+// Required scopes: users:write
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *ForbiddenError, *UnprocessableEntityError, *InternalServerErrorError.
+func (c *Client) CreateUser(ctx context.Context, body *User) (*User, error) {
+	r := &request{method: "POST", path: "/users", query: url.Values{}, header: http.Header{}}
+	r.contentType, r.body = "application/json", body
+	r.auth = "bearer"
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		403: newForbiddenError,
+		422: newUnprocessableEntityError,
+		500: newInternalServerErrorError,
+	}
+	var result User
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateUserWithID calls PUT /users to create a user with the given ID.
+//
+// Required scopes: users:write
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *ForbiddenError, *ConflictError, *UnprocessableEntityError, *InternalServerErrorError.
+func (c *Client) CreateUserWithID(ctx context.Context, body *User) (*User, error) {
+	r := &request{method: "PUT", path: "/users", query: url.Values{}, header: http.Header{}}
+	r.contentType, r.body = "application/json", body
+	r.auth = "bearer"
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		403: newForbiddenError,
+		409: newConflictError,
+		422: newUnprocessableEntityError,
+		500: newInternalServerErrorError,
+	}
+	var result User
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteUserParams are the optional parameters of DeleteUser. Zero values are not sent.
+type DeleteUserParams struct {
+	// ETag of the user to be changed
+	IfMatch string
+}
+
+// DeleteUser calls DELETE /users/{userID} to delete a user.
+//
+// Required scopes: users:delete
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *ForbiddenError, *NotFoundError, *ConflictError, *PreconditionFailedError, *InternalServerErrorError.
+func (c *Client) DeleteUser(ctx context.Context, userID int64, params *DeleteUserParams) error {
+	r := &request{method: "DELETE", path: "/users/" + url.PathEscape(fmt.Sprint(userID)), query: url.Values{}, header: http.Header{}}
+	if params != nil {
+		addParam(r.header, "If-Match", params.IfMatch, true)
+	}
+	r.auth = "bearer"
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		403: newForbiddenError,
+		404: newNotFoundError,
+		409: newConflictError,
+		412: newPreconditionFailedError,
+		500: newInternalServerErrorError,
+	}
+	return c.do(ctx, r)
+}
+
+// GetJWKS calls GET /.well-known/jwks.json to get the JSON Web Key Set.
+//
+// Declared failures are returned as *BadRequestError, *InternalServerErrorError.
+func (c *Client) GetJWKS(ctx context.Context) (*JWKS, error) {
+	r := &request{method: "GET", path: "/.well-known/jwks.json", query: url.Values{}, header: http.Header{}}
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		500: newInternalServerErrorError,
+	}
+	var result JWKS
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetUserParams are the optional parameters of GetUser. Zero values are not sent.
+type GetUserParams struct {
+	// ETag of the cached user
+	IfNoneMatch string
+}
+
+// GetUser calls GET /users/{userID} to get a user.
+//
+// Declared failures are returned as *NotModifiedError, *BadRequestError, *NotFoundError, *InternalServerErrorError.
+func (c *Client) GetUser(ctx context.Context, userID int64, params *GetUserParams) (*User, error) {
+	r := &request{method: "GET", path: "/users/" + url.PathEscape(fmt.Sprint(userID)), query: url.Values{}, header: http.Header{}}
+	if params != nil {
+		addParam(r.header, "If-None-Match", params.IfNoneMatch, true)
+	}
+	r.errors = map[int]func(http.Header, []byte) error{
+		304: newNotModifiedError,
+		400: newBadRequestError,
+		404: newNotFoundError,
+		500: newInternalServerErrorError,
+	}
+	var result User
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListUsersParams are the optional parameters of ListUsers. Zero values are not sent.
+type ListUsersParams struct {
+	// maximum number of users to return
+	Limit int64
+	// number of users to skip
+	Offset int64
+	// prefix of the names of the users
+	Name string
+	// minimum age of the users
+	MinAge int64
+	// maximum age of the users
+	MaxAge int64
+	// field to sort by; prefix with - to sort descending
+	Sort string
+}
+
+// ListUsers calls GET /users to get all users.
+//
+// Required scopes: users:read
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *ForbiddenError, *TooManyRequestsError, *InternalServerErrorError.
+func (c *Client) ListUsers(ctx context.Context, params *ListUsersParams) ([]User, error) {
+	r := &request{method: "GET", path: "/users", query: url.Values{}, header: http.Header{}}
+	if params != nil {
+		addParam(r.query, "limit", params.Limit, true)
+		addParam(r.query, "offset", params.Offset, true)
+		addParam(r.query, "name", params.Name, true)
+		addParam(r.query, "minAge", params.MinAge, true)
+		addParam(r.query, "maxAge", params.MaxAge, true)
+		addParam(r.query, "sort", params.Sort, true)
+	}
+	r.auth = "basic"
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		403: newForbiddenError,
+		429: newTooManyRequestsError,
+		500: newInternalServerErrorError,
+	}
+	var result []User
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Login calls POST /login to login.
+//
+// Declared failures are returned as *BadRequestError, *UnprocessableEntityError, *TooManyRequestsError, *InternalServerErrorError.
+func (c *Client) Login(ctx context.Context, body *LoginInfo) (*JWTToken, error) {
+	r := &request{method: "POST", path: "/login", query: url.Values{}, header: http.Header{}}
+	r.contentType, r.body = "application/json", body
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		422: newUnprocessableEntityError,
+		429: newTooManyRequestsError,
+		500: newInternalServerErrorError,
+	}
+	var result JWTToken
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Logout calls POST /login/logout to revoke the token and optionally a refresh token.
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *InternalServerErrorError.
+func (c *Client) Logout(ctx context.Context, body *RefreshToken) error {
+	r := &request{method: "POST", path: "/login/logout", query: url.Values{}, header: http.Header{}}
+	r.contentType, r.body = "application/json", body
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		500: newInternalServerErrorError,
+	}
+	return c.do(ctx, r)
+}
+
+// PatchUserParams are the optional parameters of PatchUser. Zero values are not sent.
+type PatchUserParams struct {
+	// ETag of the user to be changed
+	IfMatch string
+}
+
+// PatchUser calls PATCH /users/{userID} to patch a user.
+//
+// The body is a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) of the user.
+//
+// Required scopes: users:write
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *ForbiddenError, *NotFoundError, *ConflictError, *PreconditionFailedError, *UnsupportedMediaTypeError, *UnprocessableEntityError, *InternalServerErrorError.
+func (c *Client) PatchUser(ctx context.Context, userID int64, body map[string]interface{}, params *PatchUserParams) (*User, error) {
+	r := &request{method: "PATCH", path: "/users/" + url.PathEscape(fmt.Sprint(userID)), query: url.Values{}, header: http.Header{}}
+	if params != nil {
+		addParam(r.header, "If-Match", params.IfMatch, true)
+	}
+	r.contentType, r.body = "application/merge-patch+json", body
+	r.auth = "bearer"
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		403: newForbiddenError,
+		404: newNotFoundError,
+		409: newConflictError,
+		412: newPreconditionFailedError,
+		415: newUnsupportedMediaTypeError,
+		422: newUnprocessableEntityError,
+		500: newInternalServerErrorError,
+	}
+	var result User
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// QueryAuditLogParams are the optional parameters of QueryAuditLog. Zero values are not sent.
+type QueryAuditLogParams struct {
+	// earliest time of the events, RFC 3339
+	From time.Time
+	// time before which the events happened, RFC 3339
+	To time.Time
+	// subject that caused the events
+	Actor string
+}
+
+// QueryAuditLog calls GET /audit to query the audit log.
+//
+// Required scopes: audit:read
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *ForbiddenError, *InternalServerErrorError.
+func (c *Client) QueryAuditLog(ctx context.Context, params *QueryAuditLogParams) ([]AuditEntry, error) {
+	r := &request{method: "GET", path: "/audit", query: url.Values{}, header: http.Header{}}
+	if params != nil {
+		addParam(r.query, "from", params.From, true)
+		addParam(r.query, "to", params.To, true)
+		addParam(r.query, "actor", params.Actor, true)
+	}
+	r.auth = "bearer"
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		403: newForbiddenError,
+		500: newInternalServerErrorError,
+	}
+	var result []AuditEntry
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RefreshToken calls POST /login/refresh to exchange a refresh token for new tokens.
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *TooManyRequestsError, *InternalServerErrorError.
+func (c *Client) RefreshToken(ctx context.Context, body *RefreshToken) (*JWTToken, error) {
+	r := &request{method: "POST", path: "/login/refresh", query: url.Values{}, header: http.Header{}}
+	r.contentType, r.body = "application/json", body
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		429: newTooManyRequestsError,
+		500: newInternalServerErrorError,
+	}
+	var result JWTToken
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Register calls POST /login/register to register a user name and password.
+//
+// Declared failures are returned as *BadRequestError, *ConflictError, *UnprocessableEntityError, *TooManyRequestsError, *InternalServerErrorError.
+func (c *Client) Register(ctx context.Context, body *LoginInfo) error {
+	r := &request{method: "POST", path: "/login/register", query: url.Values{}, header: http.Header{}}
+	r.contentType, r.body = "application/json", body
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		409: newConflictError,
+		422: newUnprocessableEntityError,
+		429: newTooManyRequestsError,
+		500: newInternalServerErrorError,
+	}
+	return c.do(ctx, r)
+}
+
+// UpdateUserParams are the optional parameters of UpdateUser. Zero values are not sent.
+type UpdateUserParams struct {
+	// ETag of the user to be changed
+	IfMatch string
+}
+
+// UpdateUser calls PUT /users/{userID} to update a user.
+//
+// Required scopes: users:write
+//
+// Declared failures are returned as *BadRequestError, *UnauthorizedError, *ForbiddenError, *NotFoundError, *ConflictError, *PreconditionFailedError, *UnprocessableEntityError, *InternalServerErrorError.
+func (c *Client) UpdateUser(ctx context.Context, userID int64, body *User, params *UpdateUserParams) (*User, error) {
+	r := &request{method: "PUT", path: "/users/" + url.PathEscape(fmt.Sprint(userID)), query: url.Values{}, header: http.Header{}}
+	if params != nil {
+		addParam(r.header, "If-Match", params.IfMatch, true)
+	}
+	r.contentType, r.body = "application/json", body
+	r.auth = "bearer"
+	r.errors = map[int]func(http.Header, []byte) error{
+		400: newBadRequestError,
+		401: newUnauthorizedError,
+		403: newForbiddenError,
+		404: newNotFoundError,
+		409: newConflictError,
+		412: newPreconditionFailedError,
+		422: newUnprocessableEntityError,
+		500: newInternalServerErrorError,
+	}
+	var result User
+	r.result = &result
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
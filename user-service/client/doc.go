@@ -0,0 +1,6 @@
+// Package client calls user-service. Its code is generated from the
+// OpenAPI document of the service; regenerate it after changing the API.
+package client
+
+//go:generate go run .. -write-openapi openapi.json
+//go:generate go run ../cmd/genclient -package client -o client.go openapi.json
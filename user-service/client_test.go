@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/tangblue/wiki/user-service/clientgen"
+	"github.com/tangblue/wiki/user-service/openapi3"
+)
+
+// TestClientIsGenerated fails if package client was not regenerated after
+// the API changed. Run go generate ./client to fix it.
+func TestClientIsGenerated(t *testing.T) {
+	c := newContract(t)
+	b, err := json.MarshalIndent(c.doc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := ioutil.ReadFile("client/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(append(b, '\n'), committed) {
+		t.Fatal("client/openapi.json is out of date; run go generate ./client")
+	}
+
+	var doc openapi3.Document
+	if err := json.Unmarshal(committed, &doc); err != nil {
+		t.Fatal(err)
+	}
+	src, err := clientgen.Generate(&doc, "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := ioutil.ReadFile("client/client.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, generated) {
+		t.Fatal("client/client.go is out of date; run go generate ./client")
+	}
+}
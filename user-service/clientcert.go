@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+// ClientPrincipals maps the identities of client certificates, a SAN or
+// the subject common name, to account names. Certificates no identity of
+// which is mapped identify nobody.
+type ClientPrincipals map[string]string
+
+// LoadClientCAs returns the pool of the PEM certificates at path, which
+// verifies the certificates of clients.
+func LoadClientCAs(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New(path + ": no PEM certificates")
+	}
+	return pool, nil
+}
+
+// identities returns the names cert identifies its subject by: the DNS
+// names, email addresses and URIs of its SAN, then its common name.
+func identities(cert *x509.Certificate) []string {
+	var ids []string
+	ids = append(ids, cert.DNSNames...)
+	ids = append(ids, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		ids = append(ids, uri.String())
+	}
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, cert.Subject.CommonName)
+	}
+	return ids
+}
+
+// Principal returns the account name of the subject of cert, or "" if
+// cert identifies nobody.
+func (p ClientPrincipals) Principal(cert *x509.Certificate) string {
+	for _, id := range identities(cert) {
+		if name, ok := p[id]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// SetClientPrincipals sets the accounts clientCertAuthenticate maps client
+// certificates to.
+func (a *Auth) SetClientPrincipals(principals ClientPrincipals) {
+	a.clientPrincipals = principals
+}
+
+// clientCertAuthenticate authenticates the caller by the client
+// certificate the TLS handshake verified. The caller has the roles of the
+// account the certificate is mapped to.
+func (a *Auth) clientCertAuthenticate(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	state := req.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "A verified client certificate is required."))
+		return
+	}
+	name := a.clientPrincipals.Principal(state.VerifiedChains[0][0])
+	if name == "" {
+		problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Client certificate is not mapped to an account."))
+		return
+	}
+	var roles []string
+	if a.credentials != nil {
+		roles = a.credentials.Roles(name)
+	}
+	req.SetAttribute(attrClaims, &Claims{
+		StandardClaims: jwt.StandardClaims{Subject: name},
+		Roles:          roles,
+		Scope:          scopesOf(roles),
+	})
+	next(req, resp)
+}
+
+// clientCertAuth authenticates the route with clientCertAuthenticate. Use
+// it with restful.RouteBuilder.Do. The server must request client
+// certificates, see Config.TLS.ClientCAFile.
+func (a *Auth) clientCertAuth(b *restful.RouteBuilder) {
+	b.Filter(traceFilter("clientCertAuthenticate", a.clientCertAuthenticate)).
+		Metadata(KeySecurityScheme, securitySchemeClientCert).
+		Returns(http.StatusUnauthorized, "Not Authorized", problem.Problem{})
+}
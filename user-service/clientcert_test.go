@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+)
+
+func TestClientPrincipal(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.com/billing")
+	principals := ClientPrincipals{"client1.example.com": "admin", "spiffe://example.com/billing": "billing"}
+	for _, c := range []struct {
+		cert *x509.Certificate
+		want string
+	}{
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "client1.example.com"}}, "admin"},
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "client2.example.com"}}, ""},
+		{&x509.Certificate{DNSNames: []string{"a.example.com"}, Subject: pkix.Name{CommonName: "client1.example.com"}}, "admin"},
+		{&x509.Certificate{DNSNames: []string{"a.example.com"}, EmailAddresses: []string{"a@example.com"}}, ""},
+		{&x509.Certificate{URIs: []*url.URL{spiffe}}, "billing"},
+		{&x509.Certificate{}, ""},
+	} {
+		if got := principals.Principal(c.cert); got != c.want {
+			t.Errorf("Principal(%v %v) = %q, want %q", c.cert.DNSNames, c.cert.Subject.CommonName, got, c.want)
+		}
+	}
+}
+
+func TestClientCertAuthenticate(t *testing.T) {
+	credentials, err := NewCredentials("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.Add("admin", "password", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	audit := NewAuditLog()
+	auth := NewAuth(nil, credentials, audit, time.Minute, time.Hour, AuthLimits{})
+	auth.SetClientPrincipals(ClientPrincipals{"client1.example.com": "admin"})
+	container := restful.NewContainer()
+	container.Add(audit.WebService("/audit", []string{"audit"}, auth))
+
+	for _, c := range []struct {
+		name string
+		tls  *tls.ConnectionState
+		want int
+	}{
+		{"mapped", verified("client1.example.com"), http.StatusOK},
+		{"unmapped", verified("client2.example.com"), http.StatusUnauthorized},
+		{"unverified", &tls.ConnectionState{}, http.StatusUnauthorized},
+		{"missing", nil, http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest("GET", "/audit", nil)
+		req.TLS = c.tls
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s certificate: GET /audit = %d, want %d: %s", c.name, rec.Code, c.want, rec.Body)
+		}
+	}
+}
+
+// verified returns the state of a TLS connection whose client certificate
+// for cn was verified.
+func verified(cn string) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
@@ -0,0 +1,487 @@
+// Package clientgen generates a typed Go client of an API from its
+// OpenAPI 3.0 document.
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tangblue/goapi/spec"
+	"github.com/tangblue/wiki/user-service/openapi3"
+)
+
+// Generate returns the source of the Go package pkg with a Client calling
+// the operations of doc. The operations need operation IDs, which name
+// the methods.
+func Generate(doc *openapi3.Document, pkg string) ([]byte, error) {
+	g := &generator{doc: doc, errorTypes: map[int]string{}}
+	if err := g.collectErrors(); err != nil {
+		return nil, err
+	}
+
+	title := "the API"
+	if doc.Info != nil && doc.Info.Title != "" {
+		title = doc.Info.Title
+	}
+	g.printf("// Code generated by clientgen from the OpenAPI document of %s. DO NOT EDIT.\n\n", title)
+	g.printf("package %s\n\n", pkg)
+	g.printf("import (\n")
+	for _, imp := range []string{"bytes", "context", "encoding/json", "fmt", "io", "io/ioutil", "net/http", "net/url", "reflect", "strings", "time"} {
+		g.printf("\t%q\n", imp)
+	}
+	g.printf(")\n\n")
+	g.buf.WriteString(runtime)
+
+	if err := g.schemas(); err != nil {
+		return nil, err
+	}
+	g.errors()
+	if err := g.operations(); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("clientgen: %v\n%s", err, g.buf.Bytes())
+	}
+	return src, nil
+}
+
+type generator struct {
+	doc *openapi3.Document
+	buf bytes.Buffer
+	// errorTypes are the types of the failure statuses declared by the
+	// operations, and errorBodies the schemas of their bodies.
+	errorTypes  map[int]string
+	errorBodies map[int]*spec.Schema
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// comment writes text as a comment indented by indent.
+func (g *generator) comment(indent, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		g.printf("%s// %s\n", indent, strings.TrimSpace(line))
+	}
+}
+
+func sortedKeys(m map[string]spec.Schema) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// schemas writes a type for each schema of the components.
+func (g *generator) schemas() error {
+	names := make([]string, 0, len(g.doc.Components.Schemas))
+	for name := range g.doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := map[string]string{}
+	for _, name := range names {
+		typ := typeName(name)
+		if other, ok := seen[typ]; ok {
+			return fmt.Errorf("clientgen: schemas %s and %s are both named %s", other, name, typ)
+		}
+		seen[typ] = name
+
+		s := g.doc.Components.Schemas[name]
+		if s.Description != "" {
+			g.comment("", s.Description)
+		}
+		if isObject(s) && len(s.Properties) > 0 {
+			g.printf("type %s %s\n\n", typ, g.structType(s))
+		} else {
+			g.printf("type %s %s\n\n", typ, g.goType(s))
+		}
+	}
+	return nil
+}
+
+func isObject(s *spec.Schema) bool {
+	return s.Type.Contains("object") || (len(s.Type) == 0 && len(s.Properties) > 0)
+}
+
+// structType returns the struct type of the object schema s.
+func (g *generator) structType(s *spec.Schema) string {
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for _, name := range sortedKeys(s.Properties) {
+		p := s.Properties[name]
+		if p.Description != "" {
+			for _, line := range strings.Split(strings.TrimSpace(p.Description), "\n") {
+				b.WriteString("// " + strings.TrimSpace(line) + "\n")
+			}
+		}
+		tag := name
+		if !required[name] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "%s %s `json:%q`\n", exported(name), g.goType(&p), tag)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// goType returns the Go type of values of s.
+func (g *generator) goType(s *spec.Schema) string {
+	if s == nil {
+		return "interface{}"
+	}
+	if ref := s.Ref.String(); ref != "" {
+		return typeName(ref[strings.LastIndex(ref, "/")+1:])
+	}
+	switch {
+	case s.Type.Contains("string"):
+		if s.Format == "date-time" {
+			return "time.Time"
+		}
+		return "string"
+	case s.Type.Contains("integer"):
+		switch s.Format {
+		case "int32", "int64":
+			return s.Format
+		}
+		return "int"
+	case s.Type.Contains("number"):
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case s.Type.Contains("boolean"):
+		return "bool"
+	case s.Type.Contains("array"):
+		if s.Items == nil {
+			return "[]interface{}"
+		}
+		return "[]" + g.goType(s.Items.Schema)
+	case isObject(s):
+		if len(s.Properties) > 0 {
+			return g.structType(s)
+		}
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties.Schema)
+		}
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+// jsonContent returns the JSON media type of content and its schema,
+// preferring plain JSON, then JSON Merge Patch.
+func jsonContent(content map[string]openapi3.MediaTypeObject) (string, *spec.Schema, bool) {
+	types := make([]string, 0, len(content))
+	for typ := range content {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return jsonRank(types[i]) < jsonRank(types[j]) ||
+			jsonRank(types[i]) == jsonRank(types[j]) && types[i] < types[j]
+	})
+	if len(types) == 0 || jsonRank(types[0]) > 2 {
+		return "", nil, false
+	}
+	return types[0], content[types[0]].Schema, true
+}
+
+func jsonRank(typ string) int {
+	switch {
+	case typ == "application/json":
+		return 0
+	case typ == "application/merge-patch+json":
+		return 1
+	case strings.HasSuffix(typ, "+json"):
+		return 2
+	}
+	return 3
+}
+
+func isSuccess(code int) bool {
+	return code >= 200 && code < 300
+}
+
+// collectErrors names the error types of the failure statuses.
+func (g *generator) collectErrors() error {
+	g.errorBodies = map[int]*spec.Schema{}
+	conflicting := map[int]bool{}
+	for _, op := range g.sortedOperations() {
+		for key, resp := range op.Responses {
+			code, err := strconv.Atoi(key)
+			if err != nil || isSuccess(code) {
+				continue
+			}
+			if http.StatusText(code) == "" {
+				return fmt.Errorf("clientgen: %s declares unknown status %d", op.OperationID, code)
+			}
+			g.errorTypes[code] = exported(http.StatusText(code)) + "Error"
+			_, s, _ := jsonContent(resp.Content)
+			if prev, ok := g.errorBodies[code]; ok && g.goType(prev) != g.goType(s) {
+				conflicting[code] = true
+			}
+			g.errorBodies[code] = s
+		}
+	}
+	for code := range conflicting {
+		g.errorBodies[code] = nil
+	}
+	return nil
+}
+
+// errors writes the error types of the failure statuses.
+func (g *generator) errors() {
+	codes := make([]int, 0, len(g.errorTypes))
+	for code := range g.errorTypes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		typ, body := g.errorTypes[code], g.errorBodies[code]
+		g.printf("// %s is a %d %s response.\n", typ, code, http.StatusText(code))
+		g.printf("type %s struct {\n\tHeader http.Header\n", typ)
+		bodyType := "json.RawMessage"
+		if body != nil {
+			bodyType = g.goType(body)
+		}
+		g.printf("\tBody %s\n}\n\n", bodyType)
+
+		g.printf("func (e *%s) Error() string {\n", typ)
+		if g.hasDetail(body) {
+			g.printf("\tif e.Body.Detail != \"\" {\n\t\treturn %q + e.Body.Detail\n\t}\n", fmt.Sprintf("%d %s: ", code, http.StatusText(code)))
+		}
+		g.printf("\treturn %q\n}\n\n", fmt.Sprintf("%d %s", code, http.StatusText(code)))
+
+		g.printf("func new%s(header http.Header, body []byte) error {\n", typ)
+		g.printf("\te := &%s{Header: header}\n", typ)
+		if body != nil {
+			g.printf("\tif len(body) > 0 {\n\t\tif err := json.Unmarshal(body, &e.Body); err != nil {\n\t\t\treturn &StatusError{StatusCode: %d, Header: header, Body: body}\n\t\t}\n\t}\n", code)
+		} else {
+			g.printf("\te.Body = body\n")
+		}
+		g.printf("\treturn e\n}\n\n")
+	}
+}
+
+// hasDetail reports whether s is a problem details object, whose detail
+// explains the error.
+func (g *generator) hasDetail(s *spec.Schema) bool {
+	if s == nil {
+		return false
+	}
+	if ref := s.Ref.String(); ref != "" {
+		s = g.doc.Components.Schemas[ref[strings.LastIndex(ref, "/")+1:]]
+		if s == nil {
+			return false
+		}
+	}
+	detail, ok := s.Properties["detail"]
+	return ok && detail.Type.Contains("string") && isObject(s)
+}
+
+type operation struct {
+	*openapi3.Operation
+	method, path string
+}
+
+func (g *generator) sortedOperations() []operation {
+	var ops []operation
+	for path, item := range g.doc.Paths {
+		for method, op := range item {
+			ops = append(ops, operation{op, strings.ToUpper(method), path})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationID < ops[j].OperationID })
+	return ops
+}
+
+// operations writes a method of Client for each operation.
+func (g *generator) operations() error {
+	seen := map[string]bool{}
+	for _, op := range g.sortedOperations() {
+		if op.OperationID == "" {
+			return fmt.Errorf("clientgen: %s %s has no operation ID", op.method, op.path)
+		}
+		name := exported(op.OperationID)
+		if seen[name] {
+			return fmt.Errorf("clientgen: operations named %s twice", name)
+		}
+		seen[name] = true
+		if err := g.operation(name, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) operation(name string, op operation) error {
+	var pathParams, otherParams []*openapi3.Parameter
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			pathParams = append(pathParams, p)
+		case "header":
+			// Client sends the credentials.
+			if strings.EqualFold(p.Name, "Authorization") {
+				continue
+			}
+			otherParams = append(otherParams, p)
+		case "query":
+			otherParams = append(otherParams, p)
+		}
+	}
+	paramsType := name + "Params"
+	if len(otherParams) > 0 {
+		g.printf("// %s are the optional parameters of %s. Zero values are not sent.\n", paramsType, name)
+		g.printf("type %s struct {\n", paramsType)
+		for _, p := range otherParams {
+			if p.Description != "" {
+				g.comment("\t", p.Description)
+			}
+			g.printf("\t%s %s\n", exported(p.Name), g.goType(p.Schema))
+		}
+		g.printf("}\n\n")
+	}
+
+	args := []string{"ctx context.Context"}
+	for _, p := range pathParams {
+		args = append(args, unexported(p.Name)+" "+g.goType(p.Schema))
+	}
+	contentType, bodyType := "", ""
+	if op.RequestBody != nil {
+		typ, s, ok := jsonContent(op.RequestBody.Content)
+		if !ok {
+			return fmt.Errorf("clientgen: %s reads no JSON", op.OperationID)
+		}
+		contentType, bodyType = typ, g.goType(s)
+		// Models are passed by pointer, like the results.
+		if s != nil && s.Ref.String() != "" {
+			bodyType = "*" + bodyType
+		}
+		args = append(args, "body "+bodyType)
+	}
+	if len(otherParams) > 0 {
+		args = append(args, "params *"+paramsType)
+	}
+
+	var resultType string
+	var codes []int
+	for key, resp := range op.Responses {
+		code, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		codes = append(codes, code)
+		if _, s, ok := jsonContent(resp.Content); ok && isSuccess(code) && resultType == "" && s != nil {
+			resultType = g.goType(s)
+		}
+	}
+	sort.Ints(codes)
+
+	if op.Summary != "" {
+		g.printf("// %s calls %s %s to %s.\n", name, op.method, op.path, strings.TrimSuffix(op.Summary, "."))
+	} else {
+		g.printf("// %s calls %s %s.\n", name, op.method, op.path)
+	}
+	if op.Description != "" {
+		g.printf("//\n")
+		g.comment("", op.Description)
+	}
+	var errs []string
+	for _, code := range codes {
+		if typ, ok := g.errorTypes[code]; ok {
+			errs = append(errs, "*"+typ)
+		}
+	}
+	if len(errs) > 0 {
+		g.printf("//\n// Declared failures are returned as %s.\n", strings.Join(errs, ", "))
+	}
+
+	results, zero, ret := "error", "", "err"
+	pointer := resultType != "" && !strings.HasPrefix(resultType, "[]") && !strings.HasPrefix(resultType, "map[")
+	switch {
+	case pointer:
+		results, zero, ret = "(*"+resultType+", error)", "nil, ", "&result, nil"
+	case resultType != "":
+		results, zero, ret = "("+resultType+", error)", "nil, ", "result, nil"
+	}
+	g.printf("func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), results)
+
+	g.printf("\tr := &request{method: %q, path: %s, query: url.Values{}, header: http.Header{}}\n", op.method, pathExpr(op.path, pathParams))
+	if len(otherParams) > 0 {
+		g.printf("\tif params != nil {\n")
+		for _, p := range otherParams {
+			target := "r.query"
+			if p.In == "header" {
+				target = "r.header"
+			}
+			explode := p.Explode == nil || *p.Explode
+			g.printf("\t\taddParam(%s, %q, params.%s, %v)\n", target, p.Name, exported(p.Name), explode)
+		}
+		g.printf("\t}\n")
+	}
+	if bodyType != "" {
+		g.printf("\tr.contentType, r.body = %q, body\n", contentType)
+	}
+	if auth := g.auth(op.Security); auth != "" {
+		g.printf("\tr.auth = %q\n", auth)
+	}
+	g.printf("\tr.errors = map[int]func(http.Header, []byte) error{\n")
+	for _, code := range codes {
+		if typ, ok := g.errorTypes[code]; ok {
+			g.printf("\t\t%d: new%s,\n", code, typ)
+		}
+	}
+	g.printf("\t}\n")
+	if resultType != "" {
+		g.printf("\tvar result %s\n\tr.result = &result\n", resultType)
+		g.printf("\tif err := c.do(ctx, r); err != nil {\n\t\treturn %serr\n\t}\n\treturn %s\n}\n\n", zero, ret)
+	} else {
+		g.printf("\treturn c.do(ctx, r)\n}\n\n")
+	}
+	return nil
+}
+
+// pathExpr returns the Go expression of the templated path with the
+// path parameters.
+func pathExpr(path string, params []*openapi3.Parameter) string {
+	expr := strconv.Quote(path)
+	for _, p := range params {
+		expr = strings.Replace(expr, "{"+p.Name+"}", `" + url.PathEscape(fmt.Sprint(`+unexported(p.Name)+`)) + "`, 1)
+	}
+	return strings.TrimPrefix(strings.Replace(expr, ` + ""`, "", -1), `"" + `)
+}
+
+// auth returns how the operation authenticates: "bearer", "basic", or ""
+// if it does not.
+func (g *generator) auth(security []map[string][]string) string {
+	if len(security) == 0 {
+		security = g.doc.Security
+	}
+	for _, requirement := range security {
+		for name := range requirement {
+			s := g.doc.Components.SecuritySchemes[name]
+			switch {
+			case s == nil:
+			case s.Type == "http" && strings.EqualFold(s.Scheme, "bearer"), s.Type == "oauth2":
+				return "bearer"
+			case s.Type == "http" && strings.EqualFold(s.Scheme, "basic"):
+				return "basic"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package clientgen
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/tangblue/wiki/user-service/openapi3"
+)
+
+const testDocument = `{
+	"openapi": "3.0.3",
+	"info": {"title": "UserService", "version": "1.0.0"},
+	"paths": {
+		"/users": {
+			"get": {
+				"operationId": "listUsers",
+				"parameters": [
+					{"name": "limit", "in": "query", "schema": {"type": "integer", "format": "int32"}},
+					{"name": "sort", "in": "query", "explode": false, "schema": {"type": "array", "items": {"type": "string"}}}
+				],
+				"responses": {
+					"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/main.User"}}}}},
+					"401": {"description": "Not Authorized", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/problem.Problem"}}}}
+				},
+				"security": [{"basic": []}]
+			},
+			"post": {
+				"operationId": "createUser",
+				"requestBody": {"required": true, "content": {
+					"application/xml": {"schema": {"$ref": "#/components/schemas/main.User"}},
+					"application/json": {"schema": {"$ref": "#/components/schemas/main.User"}}
+				}},
+				"responses": {
+					"201": {"description": "Created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/main.User"}}}},
+					"422": {"description": "Invalid user", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/problem.Problem"}}}}
+				},
+				"security": [{"jwt": []}]
+			}
+		},
+		"/users/{userID}": {
+			"get": {
+				"operationId": "getUser",
+				"parameters": [
+					{"name": "userID", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}},
+					{"name": "If-None-Match", "in": "header", "schema": {"type": "string"}},
+					{"name": "Authorization", "in": "header", "schema": {"type": "string"}}
+				],
+				"responses": {
+					"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/main.User"}}}},
+					"304": {"description": "Not Modified"},
+					"404": {"description": "Not Found", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/problem.Problem"}}}}
+				}
+			},
+			"patch": {
+				"operationId": "patchUser",
+				"parameters": [{"name": "userID", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}}],
+				"requestBody": {"content": {
+					"application/json-patch+json": {"schema": {"type": "array", "items": {"type": "object"}}},
+					"application/merge-patch+json": {"schema": {"type": "object"}}
+				}},
+				"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/main.User"}}}}},
+				"security": [{"jwt": []}]
+			},
+			"delete": {
+				"operationId": "deleteUser",
+				"parameters": [{"name": "userID", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}}],
+				"responses": {"204": {"description": "No Content"}}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"main.User": {"type": "object", "required": ["name"], "properties": {
+				"id": {"type": "integer", "format": "int64"},
+				"name": {"type": "string", "description": "name of the user"}
+			}},
+			"problem.Problem": {"type": "object", "properties": {
+				"title": {"type": "string"},
+				"detail": {"type": "string"}
+			}}
+		},
+		"securitySchemes": {
+			"basic": {"type": "http", "scheme": "basic"},
+			"jwt": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"}
+		}
+	}
+}`
+
+func TestGenerate(t *testing.T) {
+	var doc openapi3.Document
+	if err := json.Unmarshal([]byte(testDocument), &doc); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(&doc, "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "client.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("client", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+
+	client := pkg.Scope().Lookup("Client").Type()
+	for name, want := range map[string]string{
+		"ListUsers":  "func(ctx context.Context, params *client.ListUsersParams) ([]client.User, error)",
+		"CreateUser": "func(ctx context.Context, body *client.User) (*client.User, error)",
+		"GetUser":    "func(ctx context.Context, userID int64, params *client.GetUserParams) (*client.User, error)",
+		"PatchUser":  "func(ctx context.Context, userID int64, body map[string]interface{}) (*client.User, error)",
+		"DeleteUser": "func(ctx context.Context, userID int64) error",
+	} {
+		obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(client), false, pkg, name)
+		if obj == nil {
+			t.Errorf("Client has no method %s", name)
+			continue
+		}
+		if got := obj.Type().String(); got != want {
+			t.Errorf("%s: %s, want %s", name, got, want)
+		}
+	}
+	for _, name := range []string{"UnauthorizedError", "UnprocessableEntityError", "NotModifiedError", "NotFoundError", "StatusError"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Errorf("no error type %s", name)
+		}
+	}
+	code := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{
+		`Name string ` + "`" + `json:"name"` + "`",
+		`ID int64 ` + "`" + `json:"id,omitempty"` + "`",
+		`r.contentType, r.body = "application/json", body`,
+		`r.contentType, r.body = "application/merge-patch+json", body`,
+		`addParam(r.query, "sort", params.Sort, false)`,
+		`addParam(r.header, "If-None-Match", params.IfNoneMatch, true)`,
+		`return "404 Not Found: " + e.Body.Detail`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+	if strings.Contains(code, "params.Authorization") {
+		t.Error("generated code sends the Authorization parameter, not the credentials of Client")
+	}
+}
+
+func TestNames(t *testing.T) {
+	for name, want := range map[string][2]string{
+		"userID":        {"UserID", "userID"},
+		"main.JWTToken": {"MainJWTToken", "mainJWTToken"},
+		"If-None-Match": {"IfNoneMatch", "ifNoneMatch"},
+		"X-Total-Count": {"XTotalCount", "xTotalCount"},
+		"id":            {"ID", "id"},
+		"expiresIn":     {"ExpiresIn", "expiresIn"},
+	} {
+		if got := exported(name); got != want[0] {
+			t.Errorf("exported(%q) = %q, want %q", name, got, want[0])
+		}
+		if got := unexported(name); got != want[1] {
+			t.Errorf("unexported(%q) = %q, want %q", name, got, want[1])
+		}
+	}
+	if got := typeName("main.JWTToken"); got != "JWTToken" {
+		t.Errorf("typeName = %q", got)
+	}
+}
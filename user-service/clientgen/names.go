@@ -0,0 +1,82 @@
+package clientgen
+
+import (
+	"strings"
+	"unicode"
+)
+
+// initialisms are the words Go writes in upper case.
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IP": true, "JSON": true, "JWKS": true,
+	"JWT": true, "TTL": true, "URI": true, "URL": true, "XML": true,
+}
+
+// exported returns the exported Go name of name, which may be camel case
+// or separated by other characters, like "main.User" or "If-Match".
+func exported(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(word)
+		b.WriteRune(unicode.ToUpper(r[0]))
+		b.WriteString(string(r[1:]))
+	}
+	if b.Len() == 0 || unicode.IsDigit([]rune(b.String())[0]) {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// unexported returns name like exported but starting in lower case.
+func unexported(name string) string {
+	s := exported(name)
+	for i, r := range s {
+		if !unicode.IsUpper(r) {
+			if i > 1 {
+				// Keep the last upper case letter of an initialism.
+				i--
+			}
+			return strings.ToLower(s[:i]) + s[i:]
+		}
+	}
+	return strings.ToLower(s)
+}
+
+// words splits name at non-alphanumeric characters and at the starts of
+// camel case words.
+func words(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// typeName returns the Go type name of the schema named name in the
+// components, without the package its model came from.
+func typeName(name string) string {
+	return exported(name[strings.LastIndex(name, ".")+1:])
+}
@@ -0,0 +1,138 @@
+package clientgen
+
+// runtime is the code of a generated client that does not depend on the
+// document.
+const runtime = `// Client calls the API at BaseURL.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Token is the bearer token sent to the operations that require one.
+	Token string
+	// Username and Password authenticate the operations that require
+	// basic authentication.
+	Username, Password string
+}
+
+// New returns a client of the API at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// StatusError is a response with a status the operation does not declare,
+// or a body that does not match the declaration.
+type StatusError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+type request struct {
+	method, path string
+	query        url.Values
+	header       http.Header
+	contentType  string
+	body         interface{}
+	// auth is "bearer", "basic" or empty.
+	auth string
+	// errors makes the errors of the declared failure statuses.
+	errors map[int]func(http.Header, []byte) error
+	result interface{}
+}
+
+func (c *Client) do(ctx context.Context, r *request) error {
+	var body io.Reader
+	if r.contentType != "" {
+		b, err := json.Marshal(r.body)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	u := c.BaseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	req, err := http.NewRequest(r.method, u, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range r.header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	switch {
+	case r.auth == "bearer" && c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case r.auth == "basic" && c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if r.result == nil || len(b) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(b, r.result); err != nil {
+			return &StatusError{StatusCode: resp.StatusCode, Header: resp.Header, Body: b}
+		}
+		return nil
+	}
+	if newError, ok := r.errors[resp.StatusCode]; ok {
+		return newError(resp.Header, b)
+	}
+	return &StatusError{StatusCode: resp.StatusCode, Header: resp.Header, Body: b}
+}
+
+// addParam adds v to values under name unless v is a zero value. The
+// elements of a slice are added one by one if explode is set, else
+// joined by commas.
+func addParam(values map[string][]string, name string, v interface{}, explode bool) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.IsZero() {
+		return
+	}
+	var list []string
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			list = append(list, formatParam(rv.Index(i).Interface()))
+		}
+		if !explode {
+			list = []string{strings.Join(list, ",")}
+		}
+	} else {
+		list = []string{formatParam(v)}
+	}
+	values[name] = append(values[name], list...)
+}
+
+func formatParam(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+`
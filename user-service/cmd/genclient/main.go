@@ -0,0 +1,74 @@
+// Command genclient writes a typed Go client of an API from its OpenAPI
+// 3.0 document, read from a file or URL.
+//
+//	genclient [-package client] [-o client.go] openapi.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tangblue/wiki/user-service/clientgen"
+	"github.com/tangblue/wiki/user-service/openapi3"
+)
+
+func main() {
+	pkg := flag.String("package", "client", "name of the generated package")
+	out := flag.String("o", "", "file to write; standard output if empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] openapi.json|URL\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	log.SetFlags(0)
+
+	b, err := read(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var doc openapi3.Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		log.Fatalf("%s: %v", flag.Arg(0), err)
+	}
+	src, err := clientgen.Generate(&doc, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func read(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return ioutil.ReadFile(source)
+	}
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", openapi3.MediaType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", source, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
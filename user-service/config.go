@@ -0,0 +1,285 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// envPrefix prefixes the environment variables named by the env tags of
+// Config.
+const envPrefix = "USER_SERVICE_"
+
+// Config configures the service. It is read from a YAML file, then from
+// the environment variables of the env tags, then from the command line.
+type Config struct {
+	// Listen is the TCP address to serve on; PublicURL is where clients
+	// reach it.
+	Listen    string `yaml:"listen" env:"LISTEN"`
+	PublicURL string `yaml:"publicURL" env:"PUBLIC_URL"`
+	// TLS is served if both files are set.
+	TLS struct {
+		CertFile string `yaml:"certFile" env:"TLS_CERT_FILE"`
+		KeyFile  string `yaml:"keyFile" env:"TLS_KEY_FILE"`
+		// ReloadInterval is how often the files are checked for a
+		// renewed certificate; 0 never checks.
+		ReloadInterval time.Duration `yaml:"reloadInterval" env:"TLS_RELOAD_INTERVAL"`
+		// RedirectListen is a TCP address redirecting HTTP to PublicURL,
+		// which must be HTTPS. Nothing listens if it is empty.
+		RedirectListen string `yaml:"redirectListen" env:"TLS_REDIRECT_LISTEN"`
+		// HSTSMaxAge is the max-age of the Strict-Transport-Security
+		// header of HTTPS responses; 0 omits the header.
+		HSTSMaxAge time.Duration `yaml:"hstsMaxAge" env:"TLS_HSTS_MAX_AGE"`
+		// ClientCAFile holds the PEM certificates verifying the client
+		// certificates of routes authenticated by them. Clients are not
+		// asked for certificates if it is empty.
+		ClientCAFile string `yaml:"clientCAFile" env:"TLS_CLIENT_CA_FILE"`
+		// ClientPrincipals maps the SANs or common names of client
+		// certificates to account names. The audit log is queried with
+		// client certificates if it is set.
+		ClientPrincipals ClientPrincipals `yaml:"clientPrincipals"`
+	} `yaml:"tls"`
+	// ShutdownTimeout is how long in-flight requests may take to finish
+	// on SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT"`
+
+	Store struct {
+		Backend string `yaml:"backend" env:"STORE"`
+		Path    string `yaml:"path" env:"STORE_PATH"`
+	} `yaml:"store"`
+	// Credentials is the file of the accounts. Files written before
+	// accounts had roles are refused; remove them and register again.
+	Credentials string `yaml:"credentials" env:"CREDENTIALS"`
+	AuditLog    string `yaml:"auditLog" env:"AUDIT_LOG"`
+	// AccessLog is the file the requests are logged to, standard error
+	// if empty.
+	AccessLog string `yaml:"accessLog" env:"ACCESS_LOG"`
+	// TraceFile is the file spans are written to as OTLP/JSON lines.
+	// Requests are not traced if it is empty.
+	TraceFile string `yaml:"traceFile" env:"TRACE_FILE"`
+	// AdminPassword is the initial password of admin, read from
+	// AdminPasswordFile if that is set. It is required while the
+	// credentials have no admin.
+	AdminPassword     string `yaml:"adminPassword" env:"ADMIN_PASSWORD"`
+	AdminPasswordFile string `yaml:"adminPasswordFile" env:"ADMIN_PASSWORD_FILE"`
+
+	JWT struct {
+		// Secret is the HMAC key used if Key is not set, read from
+		// SecretFile if that is set. A random one is made if neither is.
+		Secret     string   `yaml:"secret" env:"JWT_SECRET"`
+		SecretFile string   `yaml:"secretFile" env:"JWT_SECRET_FILE"`
+		Key        string   `yaml:"key" env:"JWT_KEY"`
+		AcceptKeys []string `yaml:"acceptKeys" env:"JWT_ACCEPT_KEYS"`
+		// RotationWindow defaults to RefreshTokenTTL.
+		RotationWindow  time.Duration `yaml:"rotationWindow" env:"JWT_ROTATION_WINDOW"`
+		TokenTTL        time.Duration `yaml:"tokenTTL" env:"TOKEN_TTL"`
+		RefreshTokenTTL time.Duration `yaml:"refreshTokenTTL" env:"REFRESH_TOKEN_TTL"`
+	} `yaml:"jwt"`
+	Limits AuthLimits `yaml:"limits"`
+
+	CORS struct {
+		// AllowedOrigins empty allows any origin.
+		AllowedOrigins []string `yaml:"allowedOrigins" env:"CORS_ALLOWED_ORIGINS"`
+	} `yaml:"cors"`
+	SwaggerUI struct {
+		// Enabled serves the embedded Swagger UI.
+		Enabled bool `yaml:"enabled" env:"SWAGGER_UI"`
+		// PersistAuthorization keeps the tokens entered in the UI across
+		// reloads of the browser.
+		PersistAuthorization bool `yaml:"persistAuthorization" env:"SWAGGER_UI_PERSIST_AUTHORIZATION"`
+	} `yaml:"swaggerUI"`
+	// Validation checks requests, and in development responses, against
+	// the OpenAPI document.
+	Validation struct {
+		Requests  bool `yaml:"requests" env:"VALIDATE_REQUESTS"`
+		Responses bool `yaml:"responses" env:"VALIDATE_RESPONSES"`
+	} `yaml:"validation"`
+	// MetricsPath serves Prometheus metrics unless it is empty.
+	MetricsPath string `yaml:"metricsPath" env:"METRICS_PATH"`
+}
+
+func DefaultConfig() *Config {
+	c := &Config{
+		Listen:          ":8080",
+		PublicURL:       "http://localhost:8080",
+		ShutdownTimeout: 30 * time.Second,
+		MetricsPath:     "/metrics",
+		Limits: AuthLimits{
+			IPRate:           2,
+			IPBurst:          20,
+			UserRate:         1,
+			UserBurst:        10,
+			LockoutThreshold: 5,
+			LockoutBase:      30 * time.Second,
+			LockoutMax:       time.Hour,
+		},
+	}
+	c.TLS.ReloadInterval = time.Minute
+	c.TLS.HSTSMaxAge = 365 * 24 * time.Hour
+	c.Store.Backend = "memory"
+	c.Store.Path = "users.log"
+	c.SwaggerUI.Enabled = true
+	c.Validation.Requests = true
+	c.JWT.TokenTTL = 15 * time.Minute
+	c.JWT.RefreshTokenTTL = 24 * time.Hour
+	return c
+}
+
+// Flags defines the command line flags of c in fs.
+func (c *Config) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Listen, "listen", c.Listen, "TCP address to listen on")
+	fs.StringVar(&c.TLS.CertFile, "tls-cert", c.TLS.CertFile, "PEM certificate chain to serve HTTPS with, such as ExampleServerMerged.crt; reloaded when it changes")
+	fs.StringVar(&c.TLS.KeyFile, "tls-key", c.TLS.KeyFile, "PEM key of the certificate")
+	fs.StringVar(&c.TLS.RedirectListen, "tls-redirect-listen", c.TLS.RedirectListen, "TCP address to redirect HTTP to HTTPS on; nothing listens if empty")
+	fs.StringVar(&c.TLS.ClientCAFile, "tls-client-ca", c.TLS.ClientCAFile, "PEM certificates verifying client certificates; clients are not asked for one if empty")
+	fs.StringVar(&c.Store.Backend, "store", c.Store.Backend, "user store backend: memory or file")
+	fs.StringVar(&c.Store.Path, "store-path", c.Store.Path, "log file of the file user store")
+	fs.StringVar(&c.Credentials, "credentials", c.Credentials, "password file; passwords are kept in memory if empty")
+	fs.StringVar(&c.AdminPassword, "admin-password", c.AdminPassword, "initial password of admin if it has none")
+	fs.DurationVar(&c.JWT.TokenTTL, "token-ttl", c.JWT.TokenTTL, "lifetime of access tokens")
+	fs.DurationVar(&c.JWT.RefreshTokenTTL, "refresh-token-ttl", c.JWT.RefreshTokenTTL, "lifetime of refresh tokens")
+	fs.StringVar(&c.JWT.Key, "jwt-key", c.JWT.Key, "PEM private key signing tokens; reloaded on SIGHUP. HMAC is used if empty")
+	fs.Var((*commaList)(&c.JWT.AcceptKeys), "jwt-accept-keys", "comma separated PEM keys or certificates of previous signing keys")
+	fs.DurationVar(&c.JWT.RotationWindow, "jwt-rotation-window", c.JWT.RotationWindow, "how long a rotated out key verifies tokens (default refresh-token-ttl)")
+	fs.StringVar(&c.AccessLog, "access-log", c.AccessLog, "JSON access log file; requests are logged to standard error if empty")
+	fs.BoolVar(&c.SwaggerUI.Enabled, "swagger-ui", c.SwaggerUI.Enabled, "serve Swagger UI")
+	fs.BoolVar(&c.Validation.Requests, "validate-requests", c.Validation.Requests, "reject requests violating the OpenAPI document")
+	fs.BoolVar(&c.Validation.Responses, "validate-responses", c.Validation.Responses, "log responses violating the OpenAPI document; for development")
+	fs.StringVar(&c.TraceFile, "trace-file", c.TraceFile, "file to write trace spans to as OTLP/JSON; requests are not traced if empty")
+	fs.StringVar(&c.AuditLog, "audit-log", c.AuditLog, "append-only audit log file; the log is kept in memory if empty")
+	fs.Float64Var(&c.Limits.IPRate, "auth-ip-rate", c.Limits.IPRate, "authentication requests per second of a client IP; 0 disables the limit")
+	fs.IntVar(&c.Limits.IPBurst, "auth-ip-burst", c.Limits.IPBurst, "authentication requests a client IP may burst")
+	fs.Float64Var(&c.Limits.UserRate, "auth-user-rate", c.Limits.UserRate, "authentication attempts per second for a user name; 0 disables the limit")
+	fs.IntVar(&c.Limits.UserBurst, "auth-user-burst", c.Limits.UserBurst, "authentication attempts a user name may burst")
+	fs.IntVar(&c.Limits.LockoutThreshold, "lockout-threshold", c.Limits.LockoutThreshold, "failed logins in a row that lock a user name out; 0 disables lockout")
+	fs.DurationVar(&c.Limits.LockoutBase, "lockout-base", c.Limits.LockoutBase, "first lockout, doubled by every further failure")
+	fs.DurationVar(&c.Limits.LockoutMax, "lockout-max", c.Limits.LockoutMax, "longest lockout")
+}
+
+// Load reads the YAML file at path, unless path is empty, and then the
+// environment into c.
+func (c *Config) Load(path string) error {
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := yaml.UnmarshalStrict(b, c); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return readEnv(reflect.ValueOf(c).Elem())
+}
+
+// LoadSecrets reads the secrets that are configured as files.
+func (c *Config) LoadSecrets() error {
+	for _, each := range []struct {
+		value *string
+		path  string
+	}{
+		{&c.AdminPassword, c.AdminPasswordFile},
+		{&c.JWT.Secret, c.JWT.SecretFile},
+	} {
+		if each.path == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(each.path)
+		if err != nil {
+			return err
+		}
+		*each.value = strings.TrimRight(string(b), "\r\n")
+	}
+	if c.JWT.RotationWindow == 0 {
+		c.JWT.RotationWindow = c.JWT.RefreshTokenTTL
+	}
+	return nil
+}
+
+// readEnv sets the fields of the struct v from the environment variables
+// named by their env tags.
+func readEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := readEnv(fv); err != nil {
+				return err
+			}
+			continue
+		}
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		s, ok := os.LookupEnv(envPrefix + name)
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, s); err != nil {
+			return fmt.Errorf("%s%s: %v", envPrefix, name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, s string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(s)
+	case []string:
+		var list commaList
+		list.Set(s)
+		v.Set(reflect.ValueOf([]string(list)))
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// commaList is a flag.Value of comma separated strings.
+type commaList []string
+
+func (l *commaList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *commaList) Set(s string) error {
+	*l = nil
+	for _, each := range strings.Split(s, ",") {
+		if each = strings.TrimSpace(each); each != "" {
+			*l = append(*l, each)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigLoad(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "jwt-secret")
+	if err := ioutil.WriteFile(secret, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	yml := `
+listen: ":9090"
+store:
+  backend: file
+jwt:
+  secretFile: ` + secret + `
+  refreshTokenTTL: 2h
+limits:
+  ipRate: 0.5
+cors:
+  allowedOrigins: [https://example.com]
+`
+	if err := ioutil.WriteFile(path, []byte(yml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envPrefix+"LISTEN", ":7070")
+	t.Setenv(envPrefix+"JWT_ACCEPT_KEYS", "a.pem, b.pem")
+	t.Setenv(envPrefix+"SWAGGER_UI", "false")
+
+	c := DefaultConfig()
+	if err := c.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Listen != ":7070" {
+		t.Errorf("Listen = %q, want the environment", c.Listen)
+	}
+	if c.Store.Backend != "file" || c.Store.Path != "users.log" {
+		t.Errorf("Store = %+v", c.Store)
+	}
+	if c.JWT.Secret != "s3cret" {
+		t.Errorf("JWT.Secret = %q, want the file", c.JWT.Secret)
+	}
+	if !reflect.DeepEqual(c.JWT.AcceptKeys, []string{"a.pem", "b.pem"}) {
+		t.Errorf("JWT.AcceptKeys = %q", c.JWT.AcceptKeys)
+	}
+	if c.JWT.RotationWindow != 2*time.Hour {
+		t.Errorf("JWT.RotationWindow = %v, want refreshTokenTTL", c.JWT.RotationWindow)
+	}
+	if c.Limits.IPRate != 0.5 || c.Limits.IPBurst != 20 {
+		t.Errorf("Limits = %+v", c.Limits)
+	}
+	if c.SwaggerUI.Enabled {
+		t.Error("SwaggerUI.Enabled = true, want the environment")
+	}
+
+	if err := DefaultConfig().Load("config.example.yaml"); err != nil {
+		t.Errorf("example: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte("lisen: :80\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := DefaultConfig().Load(path); err == nil {
+		t.Error("Load accepted an unknown key")
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restfulspec"
+	"github.com/tangblue/wiki/user-service/openapi3"
+)
+
+// contract runs requests through the services and checks the responses
+// against the OpenAPI document of the services.
+type contract struct {
+	t         *testing.T
+	container *restful.Container
+	auth      *Auth
+	users     *racyUserStore
+	doc       *openapi3.Document
+	templates map[*openapi3.Operation]*regexp.Regexp
+	covered   map[*openapi3.Operation]bool
+}
+
+func newContract(t *testing.T) *contract {
+	keys, err := NewKeySet(NewHMACKey([]byte("secret")), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := NewCredentials("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.Add("admin", "admin", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	audit := NewAuditLog()
+	auth := NewAuth(keys, credentials, audit, time.Minute, time.Hour, AuthLimits{})
+
+	registerEntityAccessors()
+	container := restful.NewContainer()
+	container.Add(auth.WebService("/login", []string{"authentication"}))
+	container.Add(auth.JWKSWebService("/.well-known", []string{"authentication"}))
+	users := &racyUserStore{UserStore: NewMemUserStore()}
+	container.Add(NewUserResource(auth, users, audit).WebService("/users", []string{"users"}))
+	container.Add(audit.WebService("/audit", []string{"audit"}, auth))
+	container.Filter(requestIDFilter)
+
+	wss := container.RegisteredWebServices()
+	swo := restfulspec.BuildSwagger(restfulspec.Config{
+		WebServices:                   wss,
+		APIPath:                       "/apidocs.json",
+		PostBuildSwaggerObjectHandler: enrichSwaggerObject(wss),
+	})
+	c := &contract{
+		t:         t,
+		container: container,
+		auth:      auth,
+		users:     users,
+		doc:       NewAPIDocs(swo, DefaultConfig().PublicURL).OpenAPI(),
+		templates: map[*openapi3.Operation]*regexp.Regexp{},
+		covered:   map[*openapi3.Operation]bool{},
+	}
+	param := regexp.MustCompile(`\\\{[^/]+\\\}`)
+	for path, item := range c.doc.Paths {
+		re := regexp.MustCompile("^" + param.ReplaceAllString(regexp.QuoteMeta(path), "[^/]+") + "$")
+		for _, op := range item {
+			c.templates[op] = re
+		}
+	}
+	return c
+}
+
+// operation returns the operation of method at path.
+func (c *contract) operation(method, path string) *openapi3.Operation {
+	u, _ := url.Parse(path)
+	for _, item := range c.doc.Paths {
+		if op := item[strings.ToLower(method)]; op != nil && c.templates[op].MatchString(u.Path) {
+			return op
+		}
+	}
+	return nil
+}
+
+// call serves a request and checks that the response has status want and
+// matches the document.
+func (c *contract) call(method, path string, header http.Header, body string, want int) *httptest.ResponseRecorder {
+	c.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Accept", restful.MIME_JSON)
+	if body != "" {
+		req.Header.Set("Content-Type", restful.MIME_JSON)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	c.container.ServeHTTP(rec, req)
+
+	if rec.Code != want {
+		c.t.Errorf("%s %s = %d, want %d: %s", method, path, rec.Code, want, rec.Body)
+	}
+	op := c.operation(method, path)
+	if op == nil {
+		c.t.Errorf("%s %s is not documented", method, path)
+		return rec
+	}
+	c.covered[op] = true
+	if err := c.doc.ValidateResponse(op, rec.Code, rec.Header(), rec.Body.Bytes()); err != nil {
+		c.t.Errorf("%s %s: %d response breaks the contract: %v", method, path, rec.Code, err)
+	}
+	return rec
+}
+
+// checkCoverage fails for the operations no call exercised.
+func (c *contract) checkCoverage() {
+	for path, item := range c.doc.Paths {
+		for method, op := range item {
+			if !c.covered[op] {
+				c.t.Errorf("%s %s was not exercised", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+// racyUserStore changes a user right after the next Get of it if race is
+// set, as a concurrent request would.
+type racyUserStore struct {
+	UserStore
+	race bool
+}
+
+func (s *racyUserStore) Get(id UID) (User, Version, error) {
+	usr, v, err := s.UserStore.Get(id)
+	if err == nil && s.race {
+		s.race = false
+		if _, err := s.UserStore.Put(usr, AnyVersion); err != nil {
+			return usr, v, err
+		}
+	}
+	return usr, v, err
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func TestContract(t *testing.T) {
+	c := newContract(t)
+	none := http.Header{}
+
+	c.call("GET", "/.well-known/jwks.json", none, "", http.StatusOK)
+
+	// Authentication
+	c.call("POST", "/login/register", none, `{"name": "jane", "password": "correct horse"}`, http.StatusCreated)
+	c.call("POST", "/login/register", none, `{"name": "jane", "password": "correct horse"}`, http.StatusConflict)
+	c.call("POST", "/login/register", none, `{"name": "jack", "password": "short"}`, http.StatusBadRequest)
+	c.call("POST", "/login/register", none, `{"name": "jack", "password": "`+strings.Repeat("x", 73)+`"}`, http.StatusBadRequest)
+	c.call("POST", "/login/register", none, `{}`, http.StatusUnprocessableEntity)
+	c.call("POST", "/login/register", none, `{"name": "", "password": "correct horse"}`, http.StatusUnprocessableEntity)
+	c.call("PUT", "/login/password", none, `{"name": "jane", "password": "correct horse", "newPassword": "battery staple"}`, http.StatusNoContent)
+	c.call("PUT", "/login/password", none, `{"name": "jane", "password": "wrong password", "newPassword": "battery staple"}`, http.StatusUnprocessableEntity)
+	c.call("POST", "/login", none, `{"name": "admin", "password": "wrong"}`, http.StatusUnprocessableEntity)
+	c.call("POST", "/login", none, `{"name": "admin"`, http.StatusBadRequest)
+
+	var tokens JWTToken
+	rec := c.call("POST", "/login", none, `{"name": "admin", "password": "admin"}`, http.StatusOK)
+	if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	rec = c.call("POST", "/login/refresh", none, `{"refreshToken": "`+tokens.RefreshToken+`"}`, http.StatusOK)
+	if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	c.call("POST", "/login/refresh", none, `{"refreshToken": "invalid"}`, http.StatusUnauthorized)
+	admin := bearer(tokens.Token)
+
+	// Users
+	basic := http.Header{"Authorization": {"Basic YWRtaW46YWRtaW4="}}
+	c.call("GET", "/users", basic, "", http.StatusOK)
+	c.call("GET", "/users", none, "", http.StatusUnauthorized)
+
+	var usr User
+	rec = c.call("POST", "/users", admin, `{"name": "john", "age": 30}`, http.StatusCreated)
+	if err := json.Unmarshal(rec.Body.Bytes(), &usr); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	path := "/users/" + strconv.FormatInt(int64(usr.ID), 10)
+	c.call("POST", "/users", admin, `{"age": 200}`, http.StatusUnprocessableEntity)
+	c.call("POST", "/users", none, `{"name": "john"}`, http.StatusUnauthorized)
+	c.call("PUT", "/users", admin, `{"id": 1000, "name": "jack"}`, http.StatusCreated)
+	c.call("PUT", "/users", admin, `{"id": 1000, "name": "jack"}`, http.StatusConflict)
+
+	etag := c.call("GET", path, none, "", http.StatusOK).Header().Get("ETag")
+	c.call("GET", path, http.Header{"If-None-Match": {etag}}, "", http.StatusNotModified)
+	c.call("GET", path, http.Header{"If-None-Match": {"W/" + etag}}, "", http.StatusNotModified)
+	c.call("GET", path, http.Header{"If-None-Match": {`"stale"`}}, "", http.StatusOK)
+	c.call("GET", "/users/999999", none, "", http.StatusNotFound)
+
+	ifMatch := func(etag string) http.Header {
+		h := bearer(tokens.Token)
+		h.Set("If-Match", etag)
+		return h
+	}
+	etag = c.call("PUT", path, ifMatch(etag), `{"name": "john", "age": 31}`, http.StatusOK).Header().Get("ETag")
+	c.call("PUT", path, ifMatch(`"stale"`), `{"name": "john", "age": 32}`, http.StatusPreconditionFailed)
+	c.call("PUT", path, ifMatch("W/"+etag), `{"name": "john", "age": 32}`, http.StatusPreconditionFailed)
+	c.call("PUT", "/users/999999", admin, `{"name": "john"}`, http.StatusNotFound)
+
+	patch := ifMatch(etag)
+	patch.Set("Content-Type", MIME_MERGE_PATCH)
+	etag = c.call("PATCH", path, patch, `{"age": 32}`, http.StatusOK).Header().Get("ETag")
+	c.call("PATCH", path, patch, `{"age": 33}`, http.StatusPreconditionFailed)
+	invalid := bearer(tokens.Token)
+	invalid.Set("Content-Type", MIME_MERGE_PATCH)
+	c.call("PATCH", path, invalid, `{"age": -1}`, http.StatusUnprocessableEntity)
+
+	c.call("DELETE", path, none, "", http.StatusUnauthorized)
+	c.call("DELETE", path, ifMatch(`"stale"`), "", http.StatusPreconditionFailed)
+	c.users.race = true
+	c.call("DELETE", path, admin, "", http.StatusConflict)
+	etag = c.call("GET", path, none, "", http.StatusOK).Header().Get("ETag")
+	c.call("DELETE", path, ifMatch(etag), "", http.StatusNoContent)
+	c.call("DELETE", path, admin, "", http.StatusNotFound)
+
+	// Audit and logout
+	c.call("GET", "/audit?actor=admin", admin, "", http.StatusOK)
+	c.call("GET", "/audit", none, "", http.StatusUnauthorized)
+	c.call("POST", "/login/logout", admin, `{"refreshToken": "`+tokens.RefreshToken+`"}`, http.StatusNoContent)
+	c.call("POST", "/login/logout", admin, "{}", http.StatusUnauthorized)
+
+	c.checkCoverage()
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrCredentialExists = errors.New("credential already exists")
+	ErrBadCredentials   = errors.New("bad user name or password")
+)
+
+type credential struct {
+	Hash  []byte   `json:"hash"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Credentials holds bcrypt hashed passwords and roles by user name. When
+// path is set, they are kept in that file as a JSON object.
+type Credentials struct {
+	mu          sync.RWMutex
+	path        string
+	credentials map[string]credential
+
+	// dummy is compared against for unknown names, so that a login with
+	// an unknown name takes as long as one with a wrong password.
+	dummy []byte
+}
+
+func NewCredentials(path string) (*Credentials, error) {
+	dummy, err := bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	c := &Credentials{
+		path:        path,
+		credentials: map[string]credential{},
+		dummy:       dummy,
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.credentials); err != nil {
+		// Files written before roles map names to bare hashes.
+		if e, ok := err.(*json.UnmarshalTypeError); ok && e.Value == "string" {
+			return nil, fmt.Errorf("%s: credentials file predates roles; remove it and register the accounts again", path)
+		}
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Verify reports whether password is the password of name.
+func (c *Credentials) Verify(name, password string) bool {
+	c.mu.RLock()
+	cred, ok := c.credentials[name]
+	c.mu.RUnlock()
+
+	hash := cred.Hash
+	if !ok {
+		hash = c.dummy
+	}
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+	return ok && err == nil
+}
+
+// Add sets the password and roles of a new name.
+func (c *Credentials) Add(name, password string, roles ...string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return c.update(name, func(cred *credential, ok bool) error {
+		if ok {
+			return ErrCredentialExists
+		}
+		cred.Hash, cred.Roles = hash, roles
+		return nil
+	})
+}
+
+// Set sets the password of name, replacing any existing one.
+func (c *Credentials) Set(name, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return c.update(name, func(cred *credential, ok bool) error {
+		cred.Hash = hash
+		return nil
+	})
+}
+
+// Change replaces the password of name if old is its current password.
+// It checks old under the lock, so that of two concurrent changes with
+// the same old password only one succeeds.
+func (c *Credentials) Change(name, old, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return c.update(name, func(cred *credential, ok bool) error {
+		current := cred.Hash
+		if !ok {
+			current = c.dummy
+		}
+		if err := bcrypt.CompareHashAndPassword(current, []byte(old)); !ok || err != nil {
+			return ErrBadCredentials
+		}
+		cred.Hash = hash
+		return nil
+	})
+}
+
+func (c *Credentials) Roles(name string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.credentials[name].Roles
+}
+
+func (c *Credentials) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.credentials)
+}
+
+func (c *Credentials) Has(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.credentials[name]
+	return ok
+}
+
+// update applies f to the credential of name and saves the result. Nothing
+// changes if f or saving fails.
+func (c *Credentials) update(name string, f func(cred *credential, ok bool) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, ok := c.credentials[name]
+	cred := old
+	if err := f(&cred, ok); err != nil {
+		return err
+	}
+	c.credentials[name] = cred
+	if err := c.save(); err != nil {
+		if ok {
+			c.credentials[name] = old
+		} else {
+			delete(c.credentials, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// save writes the credentials to a temporary file and renames it over path,
+// so that a crash never leaves a truncated file. The caller must hold c.mu.
+func (c *Credentials) save() error {
+	if c.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(c.credentials)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.path)
+}
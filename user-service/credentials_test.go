@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	c, err := NewCredentials(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("john", "password", RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("john", "other", RoleAdmin); err != ErrCredentialExists {
+		t.Fatalf("Add of a taken name: got %v, want %v", err, ErrCredentialExists)
+	}
+	if hash := c.credentials["john"].Hash; strings.Contains(string(hash), "password") {
+		t.Fatalf("password stored in the clear: %s", hash)
+	}
+	if !c.Verify("john", "password") || c.Verify("john", "wrong") || c.Verify("jane", "password") {
+		t.Fatal("Verify accepted a bad password or rejected the right one")
+	}
+
+	if err := c.Change("john", "wrong", "new password"); err != ErrBadCredentials {
+		t.Fatalf("Change with a bad password: got %v, want %v", err, ErrBadCredentials)
+	}
+	if err := c.Change("john", "password", "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Verify("john", "password") || !c.Verify("john", "new password") {
+		t.Fatal("Change did not replace the password")
+	}
+	if err := c.Change("jane", "password", "new password"); err != ErrBadCredentials {
+		t.Fatalf("Change of an unknown name: got %v, want %v", err, ErrBadCredentials)
+	}
+	errs := make(chan error, 2)
+	for _, password := range []string{"first password", "second password"} {
+		go func(password string) { errs <- c.Change("john", "new password", password) }(password)
+	}
+	if err1, err2 := <-errs, <-errs; (err1 == nil) == (err2 == nil) {
+		t.Fatalf("concurrent Changes with the same old password: got %v and %v, want one to fail", err1, err2)
+	}
+	if err := c.Set("john", "new password"); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = NewCredentials(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 1 || !c.Has("john") || !c.Verify("john", "new password") {
+		t.Fatal("credentials were not persisted")
+	}
+	if roles := c.Roles("john"); !reflect.DeepEqual(roles, []string{RoleUser}) {
+		t.Fatalf("Roles after reload = %v", roles)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	for _, c := range []struct {
+		password string
+		ok       bool
+	}{
+		{"", false},
+		{"short", false},
+		{"password", true},
+		{strings.Repeat("x", maxPasswordLength), true},
+		{strings.Repeat("x", maxPasswordLength+1), false},
+	} {
+		if p := checkPassword(c.password); (p == nil) != c.ok {
+			t.Errorf("checkPassword of %d bytes = %v, want ok %v", len(c.password), p, c.ok)
+		}
+	}
+}
+
+func TestCredentialsWithoutRoles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := ioutil.WriteFile(path, []byte(`{"john": "$2a$10$abcdefghijklmnopqrstuv"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCredentials(path); err == nil || !strings.Contains(err.Error(), "predates roles") {
+		t.Errorf("NewCredentials of a file without roles = %v, want an error saying so", err)
+	}
+}
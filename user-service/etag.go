@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+func formatETag(v Version) string {
+	return `"` + strconv.FormatUint(uint64(v), 10) + `"`
+}
+
+// etagMatch reports whether an If-Match or If-None-Match header value
+// matches the version v. RFC 7232 compares If-Match strongly, so weak
+// validators match only if weak is set, as for If-None-Match.
+func etagMatch(header string, v Version, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag := formatETag(v)
+	for _, each := range strings.Split(header, ",") {
+		each = strings.TrimSpace(each)
+		if weak {
+			each = strings.TrimPrefix(each, "W/")
+		}
+		if each == etag {
+			return true
+		}
+	}
+	return false
+}
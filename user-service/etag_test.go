@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestETagMatch(t *testing.T) {
+	for _, c := range []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"7"`, false, true},
+		{`"6", "7"`, false, true},
+		{`"6"`, false, false},
+		{`*`, false, true},
+		{`W/"7"`, false, false},
+		{`W/"7"`, true, true},
+		{`W/"6", "7"`, true, true},
+	} {
+		if got := etagMatch(c.header, 7, c.weak); got != c.want {
+			t.Errorf("etagMatch(%s, 7, %v) = %v, want %v", c.header, c.weak, got, c.want)
+		}
+	}
+}
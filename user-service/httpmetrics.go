@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/wiki/user-service/metrics"
+)
+
+// HTTPMetrics records the requests of the routes by route template, so
+// that /users/1 and /users/2 count as /users/{userID}.
+type HTTPMetrics struct {
+	requests *metrics.CounterVec
+	latency  *metrics.HistogramVec
+	size     *metrics.HistogramVec
+}
+
+func NewHTTPMetrics(r *metrics.Registry) *HTTPMetrics {
+	labels := []string{"route", "method", "status"}
+	return &HTTPMetrics{
+		requests: r.NewCounterVec("http_requests_total",
+			"Number of HTTP requests.", labels...),
+		latency: r.NewHistogramVec("http_request_duration_seconds",
+			"Time to serve HTTP requests in seconds.", metrics.DefBuckets, labels...),
+		size: r.NewHistogramVec("http_response_size_bytes",
+			"Size of HTTP response bodies in bytes.", metrics.ExponentialBuckets(100, 10, 6), labels...),
+	}
+}
+
+// Filter is a container filter recording the requests of the routes.
+// Requests that match no route are not recorded.
+func (m *HTTPMetrics) Filter(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	start := time.Now()
+	next(req, resp)
+
+	route := selectedRoutePath(req)
+	if route == "" {
+		return
+	}
+	status := resp.StatusCode()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	labels := []string{route, req.Request.Method, strconv.Itoa(status)}
+	m.requests.Add(1, labels...)
+	m.latency.Observe(time.Since(start).Seconds(), labels...)
+	m.size.Observe(float64(resp.ContentLength()), labels...)
+}
+
+// registerUserGauges registers the gauges of the users and accounts.
+func registerUserGauges(r *metrics.Registry, users UserStore, credentials *Credentials) {
+	r.NewGaugeFunc("user_service_users", "Number of users.", func() float64 {
+		list, err := users.List()
+		if err != nil {
+			return 0
+		}
+		return float64(len(list))
+	})
+	r.NewGaugeFunc("user_service_accounts", "Number of accounts that can log in.", func() float64 {
+		return float64(credentials.Len())
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/wiki/user-service/metrics"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := NewHTTPMetrics(registry)
+	ws := new(restful.WebService)
+	ws.Path("/users").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/{%s}", restful.PathParameter("userID", "identifier of the user").DataType(0)).
+		Handler(func(req *restful.Request, resp *restful.Response) {
+			if req.PathParameter("userID") == "2" {
+				resp.WriteHeader(http.StatusNotFound)
+				return
+			}
+			resp.Write([]byte("{}"))
+		}))
+	container := restful.NewContainer()
+	container.Add(ws)
+	container.Filter(m.Filter)
+
+	for _, path := range []string{"/users/1", "/users/1", "/users/2", "/nothing"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", restful.MIME_JSON)
+		container.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req := httptest.NewRequest("BREW", "/users/1", nil)
+	container.ServeHTTP(httptest.NewRecorder(), req)
+
+	var b bytes.Buffer
+	if err := registry.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`http_requests_total{route="/users/{userID}",method="GET",status="200"} 2`,
+		`http_requests_total{route="/users/{userID}",method="GET",status="404"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics lack %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `route=""`) || strings.Contains(out, "BREW") {
+		t.Errorf("metrics record requests that match no route:\n%s", out)
+	}
+}
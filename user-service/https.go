@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CertReloader serves a certificate and key from files, and reloads them
+// when the files change, so that certificates are renewed without
+// restarting. Connections keep the certificate of their handshake.
+type CertReloader struct {
+	certFile, keyFile string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+// NewCertReloader loads the PEM certificate chain at certFile, such as
+// ExampleServerMerged.crt, and its key at keyFile.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.reloadIfModified(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is the tls.Config.GetCertificate function serving the
+// current certificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// Watch checks the files every interval and reloads them if they were
+// modified. A certificate that fails to load is logged and the previous
+// one kept, as the files may be caught half written.
+func (r *CertReloader) Watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		reloaded, err := r.reloadIfModified()
+		if err != nil {
+			log.Printf("Reload TLS certificate: %v", err)
+		} else if reloaded {
+			log.Printf("TLS certificate: reloaded %s", r.certFile)
+		}
+	}
+}
+
+// reloadIfModified loads the files if their modification times changed
+// since the last attempt, and reports whether it did.
+func (r *CertReloader) reloadIfModified() (bool, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		modTimes[i] = fi.ModTime()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cert != nil && modTimes == r.modTimes {
+		return false, nil
+	}
+	// Remember the attempt, so that a bad pair is not retried until one
+	// of the files changes again.
+	r.modTimes = modTimes
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	r.cert = &cert
+	return true, nil
+}
+
+// hsts tells the browsers reaching h over TLS to keep to HTTPS for
+// maxAge.
+func hsts(h http.Handler, maxAge time.Duration) http.Handler {
+	value := "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", value)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// redirectToHTTPS redirects every request to the same path under
+// publicURL, the HTTPS URL of the service.
+func redirectToHTTPS(publicURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			// Keep the method and body.
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, publicURL+r.URL.RequestURI(), status)
+	})
+}
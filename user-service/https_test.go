@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for name and its key.
+func writeCert(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCert(t, certFile, keyFile, "a.example.com")
+
+	r, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := func() string {
+		cert, _ := r.GetCertificate(nil)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if got := subject(); got != "a.example.com" {
+		t.Fatalf("subject = %q", got)
+	}
+	if reloaded, err := r.reloadIfModified(); reloaded || err != nil {
+		t.Errorf("reloadIfModified() = %v, %v on unchanged files", reloaded, err)
+	}
+
+	// A key not matching the certificate keeps the previous certificate.
+	later := time.Now().Add(time.Minute)
+	writeCert(t, certFile, filepath.Join(dir, "other.key"), "b.example.com")
+	os.Chtimes(certFile, later, later)
+	if _, err := r.reloadIfModified(); err == nil {
+		t.Error("reloadIfModified() = nil with a mismatched key")
+	}
+	if got := subject(); got != "a.example.com" {
+		t.Errorf("subject = %q after a failed reload", got)
+	}
+
+	writeCert(t, certFile, keyFile, "b.example.com")
+	later = later.Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	if reloaded, err := r.reloadIfModified(); !reloaded || err != nil {
+		t.Fatalf("reloadIfModified() = %v, %v", reloaded, err)
+	}
+	if got := subject(); got != "b.example.com" {
+		t.Errorf("subject = %q after reloading", got)
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	h := hsts(http.NotFoundHandler(), 24*time.Hour)
+	for _, secure := range []bool{false, true} {
+		req := httptest.NewRequest("GET", "/users", nil)
+		if secure {
+			req.TLS = &tls.ConnectionState{}
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		want := ""
+		if secure {
+			want = "max-age=86400"
+		}
+		if got := w.Header().Get("Strict-Transport-Security"); got != want {
+			t.Errorf("TLS %v: Strict-Transport-Security = %q, want %q", secure, got, want)
+		}
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	h := redirectToHTTPS("https://example.com:8443")
+	for method, status := range map[string]int{"GET": http.StatusMovedPermanently, "PUT": http.StatusPermanentRedirect} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "http://example.com/users?limit=10", nil))
+		if w.Code != status {
+			t.Errorf("%s: status = %d, want %d", method, w.Code, status)
+		}
+		if got := w.Header().Get("Location"); got != "https://example.com:8443/users?limit=10" {
+			t.Errorf("%s: Location = %q", method, got)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningKey is a key that signs or verifies tokens. Keys loaded from a
+// public key or certificate can only verify.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	sign   interface{}
+	verify interface{}
+}
+
+func NewHMACKey(secret []byte) *SigningKey {
+	sum := sha256.Sum256(secret)
+	return &SigningKey{
+		ID:     "hs-" + hex.EncodeToString(sum[:8]),
+		Method: jwt.SigningMethodHS256,
+		sign:   secret,
+		verify: secret,
+	}
+}
+
+// NewSigningKey wraps an RSA, ECDSA or Ed25519 private or public key.
+func NewSigningKey(key interface{}) (*SigningKey, error) {
+	k := &SigningKey{}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.sign, k.verify = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.verify = jwt.SigningMethodRS256, key
+	case *ecdsa.PrivateKey:
+		k.sign, k.verify = key, &key.PublicKey
+	case *ecdsa.PublicKey:
+		k.verify = key
+	case ed25519.PrivateKey:
+		k.Method, k.sign, k.verify = SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Method, k.verify = SigningMethodEdDSA, key
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	if pub, ok := k.verify.(*ecdsa.PublicKey); ok {
+		switch pub.Curve {
+		case elliptic.P256():
+			k.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			k.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			k.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", pub.Curve.Params().Name)
+		}
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(k.verify)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	k.ID = base64.RawURLEncoding.EncodeToString(sum[:12])
+	return k, nil
+}
+
+// LoadSigningKey reads the first PEM block of path: a PKCS#1, SEC 1 or
+// PKCS#8 private key, a PKIX public key or a certificate.
+func LoadSigningKey(path string) (*SigningKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	k, err := NewSigningKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return k, nil
+}
+
+func (k *SigningKey) CanSign() bool {
+	return k.sign != nil
+}
+
+// JWK returns the public key in JSON Web Key format. HMAC keys have no
+// public part and yield false.
+func (k *SigningKey) JWK() (JWK, bool) {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.verify.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+type JWK struct {
+	Kty string `json:"kty" description:"key type"`
+	Kid string `json:"kid" description:"key ID"`
+	Use string `json:"use" description:"public key use"`
+	Alg string `json:"alg" description:"algorithm"`
+	N   string `json:"n,omitempty" description:"RSA modulus"`
+	E   string `json:"e,omitempty" description:"RSA exponent"`
+	Crv string `json:"crv,omitempty" description:"curve"`
+	X   string `json:"x,omitempty" description:"x coordinate or public key"`
+	Y   string `json:"y,omitempty" description:"y coordinate"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys" description:"keys that verify tokens"`
+}
+
+// KeySet signs with its current key and verifies with the current key and
+// every retired key still in its rotation window.
+type KeySet struct {
+	mu      sync.RWMutex
+	current *SigningKey
+	retired map[string]retiredKey
+	// window is how long a rotated out key keeps verifying tokens. It
+	// should cover the lifetime of the tokens it signed.
+	window time.Duration
+}
+
+type retiredKey struct {
+	*SigningKey
+	until time.Time
+}
+
+func NewKeySet(current *SigningKey, window time.Duration) (*KeySet, error) {
+	if !current.CanSign() {
+		return nil, errors.New("current key cannot sign")
+	}
+	return &KeySet{
+		current: current,
+		retired: map[string]retiredKey{},
+		window:  window,
+	}, nil
+}
+
+func (s *KeySet) Current() *SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.current
+}
+
+// Rotate makes key the current key and retires the previous one.
+func (s *KeySet) Rotate(key *SigningKey) error {
+	if !key.CanSign() {
+		return errors.New("current key cannot sign")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key.ID == s.current.ID {
+		return nil
+	}
+	s.retired[s.current.ID] = retiredKey{s.current, time.Now().Add(s.window)}
+	delete(s.retired, key.ID)
+	s.current = key
+	return nil
+}
+
+// Accept verifies tokens signed by key until the given time.
+func (s *KeySet) Accept(key *SigningKey, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retired[key.ID] = retiredKey{key, until}
+}
+
+// Lookup returns the key with the given ID if it may verify tokens.
+func (s *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid == s.current.ID {
+		return s.current, true
+	}
+	if rk, ok := s.retired[kid]; ok && time.Now().Before(rk.until) {
+		return rk.SigningKey, true
+	}
+	return nil, false
+}
+
+func (s *KeySet) JWKS() JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jwks := JWKS{Keys: []JWK{}}
+	if jwk, ok := s.current.JWK(); ok {
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	now := time.Now()
+	ids := []string{}
+	for kid, rk := range s.retired {
+		if now.Before(rk.until) {
+			ids = append(ids, kid)
+		}
+	}
+	sort.Strings(ids)
+	for _, kid := range ids {
+		if jwk, ok := s.retired[kid].JWK(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// SigningMethodEdDSA implements the EdDSA algorithm of RFC 8037 with
+// Ed25519 keys, which jwt-go lacks.
+var SigningMethodEdDSA jwt.SigningMethod = signingMethodEdDSA{}
+
+type signingMethodEdDSA struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	sig, err := priv.Sign(nil, []byte(signingString), crypto.Hash(0))
+	if err != nil {
+		return "", err
+	}
+	return jwt.EncodeSegment(sig), nil
+}
+
+func (signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, []byte(signingString), sig) {
+		return errors.New("EdDSA verification failed")
+	}
+	return nil
+}
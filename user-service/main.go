@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restfulspec"
+	"github.com/tangblue/goapi/spec"
+	"github.com/tangblue/wiki/user-service/metrics"
+	"github.com/tangblue/wiki/user-service/swaggerui"
+	"github.com/tangblue/wiki/user-service/trace"
+	"github.com/tangblue/wiki/user-service/validate"
+)
+
+func main() {
+	cfg := DefaultConfig()
+	configPath := flag.String("config", os.Getenv(envPrefix+"CONFIG"), "YAML configuration file; "+envPrefix+"* variables override it")
+	writeOpenAPI := flag.String("write-openapi", "", "write the OpenAPI 3.0 document to this file and exit")
+	cfg.Flags(flag.CommandLine)
+	flag.Parse()
+	// Flags override the file and the environment, so set them again
+	// after loading those.
+	given := map[string]string{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = f.Value.String() })
+	if err := cfg.Load(*configPath); err != nil {
+		log.Fatal(err)
+	}
+	for name, value := range given {
+		flag.Set(name, value)
+	}
+	if err := cfg.LoadSecrets(); err != nil {
+		log.Fatal(err)
+	}
+
+	users, err := NewUserStore(cfg.Store.Backend, cfg.Store.Path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	credentials, err := NewCredentials(cfg.Credentials)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Writing the OpenAPI document needs no admin.
+	if !credentials.Has("admin") && *writeOpenAPI == "" {
+		if checkPassword(cfg.AdminPassword) != nil {
+			log.Fatalf("admin password must be set and have %d to %d bytes", minPasswordLength, maxPasswordLength)
+		}
+		if err := credentials.Add("admin", cfg.AdminPassword, RoleAdmin); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	keys, err := loadKeySet(cfg.JWT.Key, cfg.JWT.Secret, cfg.JWT.AcceptKeys, cfg.JWT.RotationWindow)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.JWT.Key != "" {
+		go rotateKeyOnHUP(keys, cfg.JWT.Key)
+	}
+
+	registerEntityAccessors()
+
+	audit := NewAuditLog()
+	if cfg.AuditLog != "" {
+		if audit, err = OpenAuditLog(cfg.AuditLog); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	auth := NewAuth(keys, credentials, audit, cfg.JWT.TokenTTL, cfg.JWT.RefreshTokenTTL, cfg.Limits)
+	if len(cfg.TLS.ClientPrincipals) > 0 && cfg.TLS.ClientCAFile == "" {
+		log.Fatal("client principals need the client CA file")
+	}
+	auth.SetClientPrincipals(cfg.TLS.ClientPrincipals)
+	restful.DefaultContainer.Add(auth.WebService("/login", []string{"authentication"}))
+	restful.DefaultContainer.Add(auth.JWKSWebService("/.well-known", []string{"authentication"}))
+
+	u := NewUserResource(auth, users, audit)
+	restful.DefaultContainer.Add(u.WebService("/users", []string{"users"}))
+	restful.DefaultContainer.Add(audit.WebService("/audit", []string{"audit"}, auth))
+
+	accessLog := os.Stderr
+	if cfg.AccessLog != "" {
+		if accessLog, err = os.OpenFile(cfg.AccessLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	closers := []io.Closer{audit, accessLog}
+	if cfg.TraceFile != "" {
+		f, err := os.OpenFile(cfg.TraceFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			log.Fatal(err)
+		}
+		closers = append(closers, f)
+		tracing := NewTracing(trace.NewTracer(trace.NewFileExporter(f, "user-service")))
+		restful.DefaultContainer.Filter(tracing.Filter)
+	}
+
+	registry := metrics.NewRegistry()
+	httpMetrics := NewHTTPMetrics(registry)
+	registerUserGauges(registry, users, credentials)
+	if cfg.MetricsPath != "" {
+		http.Handle(cfg.MetricsPath, registry)
+	}
+
+	swaggerJson := "/apidocs.json"
+	config := restfulspec.Config{
+		WebServices: restful.RegisteredWebServices(),
+		APIPath:     swaggerJson,
+		PostBuildSwaggerObjectHandler: enrichSwaggerObject(restful.RegisteredWebServices())}
+	url := strings.TrimSuffix(cfg.PublicURL, "/")
+	openAPIJson := "/openapi.json"
+	docs := NewAPIDocs(restfulspec.BuildSwagger(config), url)
+	restful.DefaultContainer.Add(docs.SwaggerWebService(swaggerJson))
+	restful.DefaultContainer.Add(docs.OpenAPIWebService(openAPIJson))
+	if *writeOpenAPI != "" {
+		b, err := json.MarshalIndent(docs.OpenAPI(), "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(*writeOpenAPI, append(b, '\n'), 0644)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	basePath := "/apidocs/"
+	if cfg.SwaggerUI.Enabled {
+		ui, err := swaggerui.New(swaggerui.Config{
+			SpecURL:              openAPIJson,
+			PersistAuthorization: cfg.SwaggerUI.PersistAuthorization,
+		})
+		if err != nil {
+			log.Print(err)
+			cfg.SwaggerUI.Enabled = false
+		} else {
+			http.Handle(basePath, http.StripPrefix(basePath, ui))
+		}
+	}
+
+	// Optionally, you may need to enable CORS for the UI to work.
+	cors := restful.CrossOriginResourceSharing{
+		AllowedHeaders: []string{"Content-Type", "Accept", "X-Request-ID", "traceparent", "tracestate"},
+		ExposeHeaders:  []string{"ETag", "Location", "Link", "X-Total-Count", "X-Request-ID", "Retry-After", "traceresponse"},
+		AllowedDomains: cfg.CORS.AllowedOrigins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		CookiesAllowed: false,
+		Container:      restful.DefaultContainer}
+	restful.DefaultContainer.Filter(traceFilter("requestID", requestIDFilter))
+	restful.DefaultContainer.Filter(traceFilter("accessLog", NewAccessLog(accessLog).Filter))
+	restful.DefaultContainer.Filter(traceFilter("metrics", httpMetrics.Filter))
+	restful.DefaultContainer.Filter(traceFilter("cors", cors.Filter))
+	if cfg.Validation.Requests || cfg.Validation.Responses {
+		validator := NewOpenAPIValidator(docs.OpenAPI(), cfg.Validation.Requests, cfg.Validation.Responses)
+		restful.DefaultContainer.Filter(validator.Filter)
+	}
+
+	swaggerJson = url + swaggerJson
+	log.Printf("Get the API: " + swaggerJson)
+	log.Printf("OpenAPI 3.0: " + url + openAPIJson)
+	if cfg.SwaggerUI.Enabled {
+		log.Printf("Swagger UI : " + url + basePath)
+	}
+
+	srv := &http.Server{Addr: cfg.Listen}
+	var redirect *http.Server
+	if cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "" {
+		certs, err := NewCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if cfg.TLS.ReloadInterval > 0 {
+			go certs.Watch(cfg.TLS.ReloadInterval)
+		}
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: certs.GetCertificate}
+		if cfg.TLS.HSTSMaxAge > 0 {
+			srv.Handler = hsts(http.DefaultServeMux, cfg.TLS.HSTSMaxAge)
+		}
+		if cfg.TLS.RedirectListen != "" {
+			if !strings.HasPrefix(url, "https://") {
+				log.Fatal("redirecting to HTTPS needs an https public URL")
+			}
+			redirect = &http.Server{Addr: cfg.TLS.RedirectListen, Handler: redirectToHTTPS(url)}
+		}
+	}
+	if cfg.TLS.ClientCAFile != "" {
+		if srv.TLSConfig == nil {
+			log.Fatal("client certificates need TLS: set the certificate and key files")
+		}
+		pool, err := LoadClientCAs(cfg.TLS.ClientCAFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Routes without clientCertAuth serve clients without certificates.
+		srv.TLSConfig.ClientCAs = pool
+		srv.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if err := serve(srv, redirect, cfg.ShutdownTimeout); err != nil {
+		log.Fatal(err)
+	}
+	if c, ok := users.(io.Closer); ok {
+		closers = append(closers, c)
+	}
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// serve serves srv, over TLS if srv.TLSConfig is set, and redirect unless
+// it is nil, until SIGTERM or SIGINT. Then they stop accepting connections
+// and wait up to timeout for the requests in flight.
+func serve(srv, redirect *http.Server, timeout time.Duration) error {
+	errc := make(chan error, 2)
+	go func() {
+		if srv.TLSConfig != nil {
+			// The certificate comes from TLSConfig.GetCertificate.
+			errc <- srv.ListenAndServeTLS("", "")
+		} else {
+			errc <- srv.ListenAndServe()
+		}
+	}()
+	if redirect != nil {
+		go func() { errc <- redirect.ListenAndServe() }()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-stop:
+		log.Printf("%v: shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if redirect != nil {
+		if err := redirect.Shutdown(ctx); err != nil {
+			log.Print(err)
+		}
+	}
+	return srv.Shutdown(ctx)
+}
+
+// registerEntityAccessors checks the entities read by ReadEntity against
+// their struct tags.
+func registerEntityAccessors() {
+	restful.RegisterEntityAccessor(restful.MIME_JSON, validate.EntityAccessor(restful.NewEntityAccessorJSON(restful.MIME_JSON)))
+	restful.RegisterEntityAccessor(restful.MIME_XML, validate.EntityAccessor(restful.NewEntityAccessorXML(restful.MIME_XML)))
+}
+
+// loadKeySet returns the keys signing with the PEM key at keyPath, or with
+// the HMAC secret if keyPath is empty, and accepting the keys at
+// acceptPaths for window.
+func loadKeySet(keyPath, secret string, acceptPaths []string, window time.Duration) (*KeySet, error) {
+	var current *SigningKey
+	var err error
+	switch {
+	case keyPath != "":
+		if current, err = LoadSigningKey(keyPath); err != nil {
+			return nil, err
+		}
+	case secret != "":
+		current = NewHMACKey([]byte(secret))
+	default:
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		log.Printf("No JWT key or secret configured; tokens are signed with a random secret until restart")
+		current = NewHMACKey(b)
+	}
+	keys, err := NewKeySet(current, window)
+	if err != nil {
+		return nil, err
+	}
+
+	until := time.Now().Add(window)
+	for _, path := range acceptPaths {
+		key, err := LoadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys.Accept(key, until)
+	}
+	return keys, nil
+}
+
+func rotateKeyOnHUP(keys *KeySet, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		key, err := LoadSigningKey(path)
+		if err == nil {
+			err = keys.Rotate(key)
+		}
+		if err != nil {
+			log.Printf("Rotate signing key: %v", err)
+			continue
+		}
+		log.Printf("Signing key: %s", keys.Current().ID)
+	}
+}
+
+// enrichSwaggerObject returns the function completing the document of
+// wss.
+func enrichSwaggerObject(wss []*restful.WebService) restfulspec.PostBuildSwaggerObjectFunc {
+	return func(swo *spec.Swagger) {
+		enrichSwagger(swo, wss)
+	}
+}
+
+func enrichSwagger(swo *spec.Swagger, wss []*restful.WebService) {
+	swo.Info = &spec.Info{
+		InfoProps: spec.InfoProps{
+			Title:       "UserService",
+			Description: "Resource for managing Users",
+			Contact: &spec.ContactInfo{
+				Name:  "user",
+				Email: "user@example.com",
+				URL:   "http://example.com",
+			},
+			License: &spec.License{
+				Name: "MIT",
+				URL:  "http://mit.org",
+			},
+			Version: "1.0.0",
+		},
+	}
+	swo.Tags = []spec.Tag{
+		spec.Tag{
+			TagProps: spec.TagProps{
+				Name:        "authentication",
+				Description: "Authentication",
+			},
+		},
+		spec.Tag{
+			TagProps: spec.TagProps{
+				Name:        "users",
+				Description: "Managing users",
+			},
+		},
+		spec.Tag{
+			TagProps: spec.TagProps{
+				Name:        "audit",
+				Description: "Audit log",
+			},
+		},
+	}
+	addSecurityDefinitions(swo)
+	addSecurityRequirements(swo, wss)
+	addResponseHeaders(swo, wss)
+	addBodySchemas(swo, wss)
+	addValidationConstraints(swo, wss)
+	addAuditChangeSchema(swo)
+	sortParameterEnums(swo)
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("requests_total", "Number of requests.", "route", "status")
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	r.NewGaugeFunc("users", "Number of users.", func() float64 { return 3 })
+
+	requests.Add(1, "/users/{userID}", "200")
+	requests.Add(1, "/users/{userID}", "200")
+	requests.Add(1, `/a"b`, "404")
+	latency.Observe(0.05, "/users")
+	latency.Observe(0.5, "/users")
+	latency.Observe(5, "/users")
+
+	var b bytes.Buffer
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{route="/a\"b",status="404"} 1
+requests_total{route="/users/{userID}",status="200"} 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/users",le="0.1"} 1
+latency_seconds_bucket{route="/users",le="1"} 2
+latency_seconds_bucket{route="/users",le="+Inf"} 3
+latency_seconds_sum{route="/users"} 5.55
+latency_seconds_count{route="/users"} 3
+# HELP users Number of users.
+# TYPE users gauge
+users 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("Write =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/spec"
+	"github.com/tangblue/wiki/user-service/openapi3"
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+// APIDocs serves the API as Swagger 2.0 and OpenAPI 3.0 documents.
+type APIDocs struct {
+	swagger *spec.Swagger
+	openAPI *openapi3.Document
+}
+
+// NewAPIDocs returns the documents of swo, served at publicURL.
+func NewAPIDocs(swo *spec.Swagger, publicURL string) *APIDocs {
+	doc := openapi3.FromSwagger(swo, securitySchemeJWT)
+	doc.Servers = []openapi3.Server{{URL: publicURL}}
+	addPatchBodies(doc)
+	addProblemContent(doc)
+	return &APIDocs{swagger: swo, openAPI: doc}
+}
+
+// OpenAPI returns the OpenAPI 3.0 document.
+func (d *APIDocs) OpenAPI() *openapi3.Document {
+	return d.openAPI
+}
+
+// SwaggerWebService serves the Swagger 2.0 document at path, or the
+// OpenAPI 3.0 one to clients that accept it.
+func (d *APIDocs) SwaggerWebService(path string) *restful.WebService {
+	ws := new(restful.WebService)
+	ws.Path(path).
+		Produces(restful.MIME_JSON, openapi3.MediaType)
+
+	ws.Route(ws.GET("/").Handler(d.findSwagger))
+	return ws
+}
+
+// OpenAPIWebService serves the OpenAPI 3.0 document at path.
+func (d *APIDocs) OpenAPIWebService(path string) *restful.WebService {
+	ws := new(restful.WebService)
+	ws.Path(path).
+		Produces(restful.MIME_JSON, openapi3.MediaType)
+
+	ws.Route(ws.GET("/").Handler(d.findOpenAPI))
+	return ws
+}
+
+func (d *APIDocs) findSwagger(req *restful.Request, resp *restful.Response) {
+	resp.AddHeader("Vary", "Accept")
+	if acceptsOpenAPI3(req.Request.Header.Get("Accept")) {
+		d.findOpenAPI(req, resp)
+		return
+	}
+	resp.WriteAsJson(d.swagger)
+}
+
+func (d *APIDocs) findOpenAPI(req *restful.Request, resp *restful.Response) {
+	contentType := restful.MIME_JSON
+	if acceptsOpenAPI3(req.Request.Header.Get("Accept")) {
+		contentType = openapi3.MediaType + ";version=3.0"
+	}
+	resp.WriteHeaderAndJson(http.StatusOK, d.openAPI, contentType)
+}
+
+// acceptsOpenAPI3 reports whether the Accept header accept asks for
+// OpenAPI 3 documents.
+func acceptsOpenAPI3(accept string) bool {
+	for _, each := range strings.Split(accept, ",") {
+		typ, params, err := mime.ParseMediaType(each)
+		if err == nil && typ == openapi3.MediaType && (params["version"] == "" || strings.HasPrefix(params["version"], "3")) {
+			return true
+		}
+	}
+	return false
+}
+
+// addPatchBodies documents the bodies of the PATCH operations, which read
+// the request body themselves: a JSON Merge Patch is an object and a JSON
+// Patch an array of operations.
+func addPatchBodies(doc *openapi3.Document) {
+	mergePatch := &spec.Schema{}
+	mergePatch.Type = spec.StringOrArray{"object"}
+	operation := &spec.Schema{}
+	operation.Type = spec.StringOrArray{"object"}
+	operation.Required = []string{"op", "path"}
+	operation.Properties = map[string]spec.Schema{
+		"op":    *spec.StringProperty().WithEnum("add", "remove", "replace", "move", "copy", "test"),
+		"path":  *spec.StringProperty(),
+		"from":  *spec.StringProperty(),
+		"value": {},
+	}
+	jsonPatch := spec.ArrayProperty(operation)
+
+	for _, item := range doc.Paths {
+		if op := item["patch"]; op != nil && op.RequestBody == nil {
+			op.RequestBody = &openapi3.RequestBody{
+				Required: true,
+				Content: map[string]openapi3.MediaTypeObject{
+					MIME_MERGE_PATCH: {Schema: mergePatch},
+					MIME_JSON_PATCH:  {Schema: jsonPatch},
+				},
+			}
+		}
+	}
+}
+
+// addProblemContent documents the responses of problem.Problem as the
+// application/problem+json problem.Write sends, not as the media types
+// the operations produce.
+func addProblemContent(doc *openapi3.Document) {
+	const ref = "#/components/schemas/problem.Problem"
+	for _, item := range doc.Paths {
+		for _, op := range item {
+			for _, resp := range op.Responses {
+				for _, content := range resp.Content {
+					if content.Schema != nil && content.Schema.Ref.String() == ref {
+						resp.Content = map[string]openapi3.MediaTypeObject{problem.MIME_PROBLEM_JSON: content}
+						break
+					}
+				}
+			}
+		}
+	}
+}
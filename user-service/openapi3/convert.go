@@ -0,0 +1,322 @@
+package openapi3
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+// FromSwagger converts swo to OpenAPI 3.0. The security schemes named
+// bearer become HTTP bearer schemes of JWTs, and since only OAuth2
+// requirements may list scopes, the scopes of the others are moved to the
+// descriptions of the operations.
+func FromSwagger(swo *spec.Swagger, bearer ...string) *Document {
+	doc := &Document{
+		OpenAPI:  Version,
+		Info:     swo.Info,
+		Paths:    map[string]PathItem{},
+		Security: swo.Security,
+		Tags:     swo.Tags,
+	}
+	if swo.Host != "" {
+		schemes := swo.Schemes
+		if len(schemes) == 0 {
+			schemes = []string{"http"}
+		}
+		for _, scheme := range schemes {
+			doc.Servers = append(doc.Servers, Server{URL: scheme + "://" + swo.Host + swo.BasePath})
+		}
+	} else if swo.BasePath != "" && swo.BasePath != "/" {
+		doc.Servers = []Server{{URL: swo.BasePath}}
+	}
+
+	if len(swo.Definitions) > 0 {
+		doc.Components.Schemas = map[string]*spec.Schema{}
+		for name, s := range swo.Definitions {
+			s := s
+			doc.Components.Schemas[name] = convertSchema(&s)
+		}
+	}
+	if len(swo.SecurityDefinitions) > 0 {
+		doc.Components.SecuritySchemes = map[string]*SecurityScheme{}
+		for name, s := range swo.SecurityDefinitions {
+			doc.Components.SecuritySchemes[name] = convertSecurityScheme(s, contains(bearer, name))
+		}
+	}
+
+	if swo.Paths != nil {
+		for path, item := range swo.Paths.Paths {
+			v3 := PathItem{}
+			for method, op := range operations(item) {
+				v3[strings.ToLower(method)] = convertOperation(swo, item.Parameters, op)
+			}
+			doc.Paths[path] = v3
+		}
+	}
+	for _, item := range doc.Paths {
+		for _, op := range item {
+			moveScopes(doc, op)
+		}
+	}
+	return doc
+}
+
+// Operation returns the operation of method at the templated path, or nil.
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+func operations(item spec.PathItem) map[string]*spec.Operation {
+	ops := map[string]*spec.Operation{}
+	for method, op := range map[string]*spec.Operation{
+		http.MethodGet:     item.Get,
+		http.MethodPut:     item.Put,
+		http.MethodPost:    item.Post,
+		http.MethodDelete:  item.Delete,
+		http.MethodOptions: item.Options,
+		http.MethodHead:    item.Head,
+		http.MethodPatch:   item.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+func convertOperation(swo *spec.Swagger, common []spec.Parameter, op *spec.Operation) *Operation {
+	v3 := &Operation{
+		Tags:        op.Tags,
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: op.ID,
+		Deprecated:  op.Deprecated,
+		Responses:   map[string]*Response{},
+	}
+	for _, requirement := range op.Security {
+		copied := map[string][]string{}
+		for name, scopes := range requirement {
+			copied[name] = append([]string{}, scopes...)
+		}
+		v3.Security = append(v3.Security, copied)
+	}
+	consumes := orDefault(op.Consumes, swo.Consumes)
+	produces := orDefault(op.Produces, swo.Produces)
+
+	var form *spec.Schema
+	for _, p := range append(append([]spec.Parameter(nil), common...), op.Parameters...) {
+		switch p.In {
+		case "body":
+			v3.RequestBody = &RequestBody{
+				Description: p.Description,
+				Required:    p.Required,
+				Content:     content(consumes, convertSchema(p.Schema)),
+			}
+		case "formData":
+			if form == nil {
+				form = &spec.Schema{}
+				form.Type = spec.StringOrArray{"object"}
+				form.Properties = map[string]spec.Schema{}
+			}
+			form.Properties[p.Name] = *paramSchema(p)
+			if p.Required {
+				form.Required = append(form.Required, p.Name)
+			}
+		default:
+			v3.Parameters = append(v3.Parameters, convertParameter(p))
+		}
+	}
+	if form != nil {
+		v3.RequestBody = &RequestBody{Content: content(consumes, form)}
+	}
+
+	if op.Responses != nil {
+		if op.Responses.Default != nil {
+			v3.Responses["default"] = convertResponse(*op.Responses.Default, produces)
+		}
+		for code, resp := range op.Responses.StatusCodeResponses {
+			v3.Responses[strconv.Itoa(code)] = convertResponse(resp, produces)
+		}
+	}
+	return v3
+}
+
+func orDefault(list, def []string) []string {
+	if len(list) > 0 {
+		return list
+	}
+	if len(def) > 0 {
+		return def
+	}
+	return []string{"application/json"}
+}
+
+func content(types []string, s *spec.Schema) map[string]MediaTypeObject {
+	c := map[string]MediaTypeObject{}
+	for _, typ := range types {
+		c[typ] = MediaTypeObject{Schema: s}
+	}
+	return c
+}
+
+func convertParameter(p spec.Parameter) *Parameter {
+	v3 := &Parameter{
+		Name:        p.Name,
+		In:          p.In,
+		Description: p.Description,
+		Required:    p.Required || p.In == "path",
+		Schema:      paramSchema(p),
+	}
+	if p.Type == "array" {
+		explode := p.CollectionFormat == "multi"
+		switch p.CollectionFormat {
+		case "ssv":
+			v3.Style = "spaceDelimited"
+		case "pipes":
+			v3.Style = "pipeDelimited"
+		}
+		v3.Explode = &explode
+	}
+	return v3
+}
+
+// paramSchema returns the schema of the type and validations of a
+// parameter that is not in the body.
+func paramSchema(p spec.Parameter) *spec.Schema {
+	s := &spec.Schema{}
+	s.Type = spec.StringOrArray{p.Type}
+	s.Format = p.Format
+	s.Default = p.Default
+	s.Maximum, s.ExclusiveMaximum = p.Maximum, p.ExclusiveMaximum
+	s.Minimum, s.ExclusiveMinimum = p.Minimum, p.ExclusiveMinimum
+	s.MaxLength, s.MinLength = p.MaxLength, p.MinLength
+	s.Pattern = p.Pattern
+	s.MaxItems, s.MinItems = p.MaxItems, p.MinItems
+	s.UniqueItems = p.UniqueItems
+	s.MultipleOf = p.MultipleOf
+	s.Enum = p.Enum
+	if p.Items != nil {
+		s.Items = &spec.SchemaOrArray{Schema: itemsSchema(p.Items)}
+	}
+	return s
+}
+
+func itemsSchema(items *spec.Items) *spec.Schema {
+	s := &spec.Schema{}
+	s.Type = spec.StringOrArray{items.Type}
+	s.Format = items.Format
+	s.Default = items.Default
+	s.Maximum, s.Minimum = items.Maximum, items.Minimum
+	s.MaxLength, s.MinLength = items.MaxLength, items.MinLength
+	s.Pattern = items.Pattern
+	s.Enum = items.Enum
+	if items.Items != nil {
+		s.Items = &spec.SchemaOrArray{Schema: itemsSchema(items.Items)}
+	}
+	return s
+}
+
+func convertResponse(resp spec.Response, produces []string) *Response {
+	v3 := &Response{Description: resp.Description}
+	if resp.Schema != nil {
+		v3.Content = content(produces, convertSchema(resp.Schema))
+	}
+	for name, h := range resp.Headers {
+		s := &spec.Schema{}
+		s.Type = spec.StringOrArray{h.Type}
+		s.Format = h.Format
+		if v3.Headers == nil {
+			v3.Headers = map[string]*Header{}
+		}
+		v3.Headers[name] = &Header{Description: h.Description, Schema: s}
+	}
+	return v3
+}
+
+var (
+	definitionsRef = []byte(`"$ref":"#/definitions/`)
+	schemasRef     = []byte(`"$ref":"#/components/schemas/`)
+)
+
+// convertSchema returns a copy of s referring to the schemas of the
+// components instead of the definitions.
+func convertSchema(s *spec.Schema) *spec.Schema {
+	if s == nil {
+		return nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return s
+	}
+	var v3 spec.Schema
+	if err := json.Unmarshal(bytes.Replace(b, definitionsRef, schemasRef, -1), &v3); err != nil {
+		return s
+	}
+	return &v3
+}
+
+func convertSecurityScheme(s *spec.SecurityScheme, bearer bool) *SecurityScheme {
+	v3 := &SecurityScheme{Description: s.Description}
+	switch {
+	case s.Type == "basic":
+		v3.Type, v3.Scheme = "http", "basic"
+	case bearer:
+		v3.Type, v3.Scheme, v3.BearerFormat = "http", "bearer", "JWT"
+	case s.Type == "apiKey":
+		v3.Type, v3.Name, v3.In = "apiKey", s.Name, s.In
+	case s.Type == "oauth2":
+		v3.Type = "oauth2"
+		flow := &OAuthFlow{AuthorizationURL: s.AuthorizationURL, TokenURL: s.TokenURL, Scopes: s.Scopes}
+		if flow.Scopes == nil {
+			flow.Scopes = map[string]string{}
+		}
+		v3.Flows = &OAuthFlows{}
+		switch s.Flow {
+		case "implicit":
+			v3.Flows.Implicit = flow
+		case "password":
+			v3.Flows.Password = flow
+		case "application":
+			v3.Flows.ClientCredentials = flow
+		case "accessCode":
+			v3.Flows.AuthorizationCode = flow
+		}
+	default:
+		v3.Type = s.Type
+	}
+	return v3
+}
+
+// moveScopes moves the scopes of the security requirements of op that are
+// not OAuth2 to its description.
+func moveScopes(doc *Document, op *Operation) {
+	var moved []string
+	for _, requirement := range op.Security {
+		for name, scopes := range requirement {
+			if s, ok := doc.Components.SecuritySchemes[name]; ok && s.Type == "oauth2" || len(scopes) == 0 {
+				continue
+			}
+			moved = append(moved, scopes...)
+			requirement[name] = []string{}
+		}
+	}
+	if len(moved) == 0 {
+		return
+	}
+	sort.Strings(moved)
+	op.Description = strings.TrimSpace(op.Description + "\n\nRequired scopes: " + strings.Join(moved, ", "))
+}
+
+func contains(list []string, s string) bool {
+	for _, each := range list {
+		if each == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package openapi3
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+func TestFromSwagger(t *testing.T) {
+	var swo spec.Swagger
+	if err := json.Unmarshal([]byte(`{
+		"swagger": "2.0",
+		"info": {"title": "UserService", "version": "1.0.0"},
+		"consumes": ["application/json", "application/xml"],
+		"produces": ["application/json", "application/xml"],
+		"paths": {
+			"/users/{userID}": {
+				"put": {
+					"operationId": "updateUser",
+					"parameters": [
+						{"name": "userID", "in": "path", "type": "integer", "minimum": 1},
+						{"name": "body", "in": "body", "required": true, "schema": {"$ref": "#/definitions/main.User"}}
+					],
+					"responses": {
+						"200": {"description": "OK", "schema": {"$ref": "#/definitions/main.User"},
+							"headers": {"ETag": {"type": "string", "description": "version of the user"}}},
+						"401": {"description": "Not Authorized"}
+					},
+					"security": [{"jwt": ["users:write"]}]
+				}
+			},
+			"/users": {
+				"get": {
+					"parameters": [{"name": "sort", "in": "query", "type": "array", "items": {"type": "string"}, "collectionFormat": "csv"}],
+					"responses": {"200": {"description": "OK"}},
+					"security": [{"basic": []}]
+				}
+			}
+		},
+		"definitions": {
+			"main.User": {"type": "object", "properties": {
+				"name": {"type": "string", "maxLength": 64},
+				"friends": {"type": "array", "items": {"$ref": "#/definitions/main.User"}}
+			}}
+		},
+		"securityDefinitions": {
+			"basic": {"type": "basic"},
+			"jwt": {"type": "oauth2", "flow": "password", "tokenUrl": "/login", "scopes": {"users:write": "write"}}
+		}
+	}`), &swo); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := FromSwagger(&swo, "jwt")
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "#/definitions/") {
+		t.Errorf("document refers to definitions: %s", b)
+	}
+
+	put := doc.Operation("PUT", "/users/{userID}")
+	if put == nil {
+		t.Fatal("no PUT /users/{userID}")
+	}
+	if len(put.Parameters) != 1 || !put.Parameters[0].Required || put.Parameters[0].Schema.Minimum == nil {
+		t.Errorf("parameters = %+v", put.Parameters)
+	}
+	body := put.RequestBody
+	if body == nil || !body.Required || len(body.Content) != 2 {
+		t.Fatalf("requestBody = %+v", body)
+	}
+	if ref := body.Content["application/xml"].Schema.Ref.String(); ref != "#/components/schemas/main.User" {
+		t.Errorf("body schema = %q", ref)
+	}
+	ok := put.Responses["200"]
+	if ok == nil || len(ok.Content) != 2 || ok.Headers["ETag"] == nil {
+		t.Errorf("200 = %+v", ok)
+	}
+	if r := put.Responses["401"]; r == nil || r.Content != nil {
+		t.Errorf("401 = %+v", r)
+	}
+	if scopes := put.Security[0]["jwt"]; len(scopes) != 0 || !strings.Contains(put.Description, "users:write") {
+		t.Errorf("bearer requirement = %v, description = %q", scopes, put.Description)
+	}
+
+	get := doc.Operation("GET", "/users")
+	if p := get.Parameters[0]; p.Explode == nil || *p.Explode || p.Schema.Items == nil {
+		t.Errorf("sort = %+v", p)
+	}
+
+	schemes := doc.Components.SecuritySchemes
+	if s := schemes["basic"]; s.Type != "http" || s.Scheme != "basic" {
+		t.Errorf("basic = %+v", s)
+	}
+	if s := schemes["jwt"]; s.Type != "http" || s.Scheme != "bearer" || s.BearerFormat != "JWT" {
+		t.Errorf("jwt = %+v", s)
+	}
+	friends := doc.Components.Schemas["main.User"].Properties["friends"]
+	if ref := friends.Items.Schema.Ref.String(); ref != "#/components/schemas/main.User" {
+		t.Errorf("friends items = %q", ref)
+	}
+}
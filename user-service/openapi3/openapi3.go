@@ -0,0 +1,103 @@
+// Package openapi3 models OpenAPI 3.0 documents and converts Swagger 2.0
+// documents to them.
+package openapi3
+
+import (
+	"github.com/tangblue/goapi/spec"
+)
+
+// Version is the OpenAPI version of the documents.
+const Version = "3.0.3"
+
+// MediaType is the media type of OpenAPI documents in JSON.
+const MediaType = "application/vnd.oai.openapi+json"
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       *spec.Info            `json:"info,omitempty"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+	Tags       []spec.Tag            `json:"tags,omitempty"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps the lower case HTTP methods of a path to their operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string       `json:"name"`
+	In          string       `json:"in"`
+	Description string       `json:"description,omitempty"`
+	Required    bool         `json:"required,omitempty"`
+	Style       string       `json:"style,omitempty"`
+	Explode     *bool        `json:"explode,omitempty"`
+	Schema      *spec.Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Description string                     `json:"description,omitempty"`
+	Required    bool                       `json:"required,omitempty"`
+	Content     map[string]MediaTypeObject `json:"content"`
+}
+
+// MediaTypeObject describes a body of a media type.
+type MediaTypeObject struct {
+	Schema *spec.Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string                     `json:"description"`
+	Headers     map[string]*Header         `json:"headers,omitempty"`
+	Content     map[string]MediaTypeObject `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string       `json:"description,omitempty"`
+	Schema      *spec.Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*spec.Schema    `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string      `json:"type"`
+	Description  string      `json:"description,omitempty"`
+	Name         string      `json:"name,omitempty"`
+	In           string      `json:"in,omitempty"`
+	Scheme       string      `json:"scheme,omitempty"`
+	BearerFormat string      `json:"bearerFormat,omitempty"`
+	Flows        *OAuthFlows `json:"flows,omitempty"`
+}
+
+type OAuthFlows struct {
+	Implicit          *OAuthFlow `json:"implicit,omitempty"`
+	Password          *OAuthFlow `json:"password,omitempty"`
+	ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty"`
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+}
+
+type OAuthFlow struct {
+	AuthorizationURL string            `json:"authorizationUrl,omitempty"`
+	TokenURL         string            `json:"tokenUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes"`
+}
@@ -0,0 +1,145 @@
+package openapi3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+// ErrBodyRequired is returned for a request without the body its
+// operation requires.
+var ErrBodyRequired = errors.New("body is required")
+
+// ValidateParameters checks the path, query and header parameters of a
+// request for op; pathParams holds the values of the path. It returns
+// ValidationErrors pointing at /<in>/<name>, or nil.
+func (d *Document) ValidateParameters(op *Operation, r *http.Request, pathParams map[string]string) error {
+	var errs ValidationErrors
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		ptr := "/" + p.In + "/" + escapePointer(p.Name)
+		var values []string
+		switch p.In {
+		case "path":
+			if v, ok := pathParams[p.Name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = query[p.Name]
+		case "header":
+			// The Authorization header is described by the security
+			// schemes, whatever a parameter says.
+			if strings.EqualFold(p.Name, "Authorization") {
+				continue
+			}
+			values = r.Header[http.CanonicalHeaderKey(p.Name)]
+		default:
+			continue
+		}
+		if len(values) == 0 {
+			if p.Required {
+				errs = append(errs, ValidationError{Pointer: ptr, Detail: "is required"})
+			}
+			continue
+		}
+		v, err := d.parseParameter(p, values)
+		if err != nil {
+			errs = append(errs, ValidationError{Pointer: ptr, Detail: err.Error()})
+			continue
+		}
+		d.validate(p.Schema, v, ptr, &errs, 0)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// parseParameter returns the values of a parameter as the JSON value its
+// schema describes.
+func (d *Document) parseParameter(p *Parameter, values []string) (interface{}, error) {
+	s, err := d.resolve(p.Schema)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || !s.Type.Contains("array") {
+		return parseScalar(s, values[0])
+	}
+
+	explode := p.Explode == nil && p.In == "query" || p.Explode != nil && *p.Explode
+	if !explode || len(values) == 1 {
+		sep := ","
+		switch p.Style {
+		case "spaceDelimited":
+			sep = " "
+		case "pipeDelimited":
+			sep = "|"
+		}
+		values = strings.Split(strings.Join(values, sep), sep)
+	}
+	var items *spec.Schema
+	if s.Items != nil {
+		if items, err = d.resolve(s.Items.Schema); err != nil {
+			return nil, err
+		}
+	}
+	list := make([]interface{}, len(values))
+	for i, value := range values {
+		if list[i], err = parseScalar(items, value); err != nil {
+			return nil, fmt.Errorf("item %d %v", i, err)
+		}
+	}
+	return list, nil
+}
+
+func parseScalar(s *spec.Schema, value string) (interface{}, error) {
+	switch {
+	case s == nil:
+		return value, nil
+	case s.Type.Contains("integer"), s.Type.Contains("number"):
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return json.Number(value), nil
+	case s.Type.Contains("boolean"):
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+// ValidateBody checks a request body of mediaType for op. Bodies of media
+// types other than JSON are only checked to be declared. It returns
+// ValidationErrors pointing into the body if it violates its schema.
+func (d *Document) ValidateBody(op *Operation, mediaType string, body []byte) error {
+	rb := op.RequestBody
+	if rb == nil {
+		return nil
+	}
+	if len(body) == 0 {
+		if rb.Required {
+			return ErrBodyRequired
+		}
+		return nil
+	}
+	content, ok := rb.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("media type %s is not accepted", mediaType)
+	}
+	if !isJSON(mediaType) || content.Schema == nil {
+		return nil
+	}
+	v, err := DecodeJSON(body)
+	if err != nil {
+		return err
+	}
+	return d.Validate(content.Schema, v)
+}
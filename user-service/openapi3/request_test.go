@@ -0,0 +1,79 @@
+package openapi3
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateParameters(t *testing.T) {
+	var doc Document
+	if err := json.Unmarshal([]byte(`{
+		"openapi": "3.0.3",
+		"paths": {"/users/{userID}": {"get": {
+			"parameters": [
+				{"name": "userID", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 1}},
+				{"name": "limit", "in": "query", "schema": {"type": "integer", "maximum": 100}},
+				{"name": "sort", "in": "query", "explode": false, "schema": {"type": "array", "items": {"type": "string", "enum": ["name", "age"]}}},
+				{"name": "verbose", "in": "query", "schema": {"type": "boolean"}},
+				{"name": "If-Match", "in": "header", "required": true, "schema": {"type": "string"}},
+				{"name": "Authorization", "in": "header", "required": true, "schema": {"type": "string"}}
+			],
+			"responses": {"200": {"description": "OK"}}
+		}}}
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Operation("GET", "/users/{userID}")
+
+	for _, c := range []struct {
+		id, query, ifMatch string
+		want               string
+	}{
+		{"1", "limit=10&sort=name,age&verbose=true", `"1"`, ""},
+		{"1", "", "", "/header/If-Match: is required"},
+		{"0", "limit=101", `"1"`, "/path/userID: must be >= 1; /query/limit: must be <= 100"},
+		{"x", "limit=ten", `"1"`, "/path/userID: must be a number; /query/limit: must be a number"},
+		{"1", "sort=name,id&verbose=yes", `"1"`, `/query/sort/1: must be one of "name", "age"; /query/verbose: must be true or false`},
+	} {
+		r := httptest.NewRequest("GET", "/users/"+c.id+"?"+c.query, nil)
+		if c.ifMatch != "" {
+			r.Header.Set("If-Match", c.ifMatch)
+		}
+		got := ""
+		if err := doc.ValidateParameters(op, r, map[string]string{"userID": c.id}); err != nil {
+			got = err.Error()
+		}
+		if got != c.want {
+			t.Errorf("ValidateParameters(%s?%s) = %q, want %q", c.id, c.query, got, c.want)
+		}
+	}
+}
+
+func TestValidateBody(t *testing.T) {
+	doc := testDocument(t)
+	op := &Operation{RequestBody: &RequestBody{Required: true, Content: map[string]MediaTypeObject{
+		"application/json": {Schema: doc.Components.Schemas["User"]},
+		"application/xml":  {},
+	}}}
+
+	for _, c := range []struct {
+		mediaType, body string
+		want            string
+	}{
+		{"application/json", `{"name": "john"}`, ""},
+		{"application/xml", `<User/>`, ""},
+		{"", ``, "body is required"},
+		{"text/plain", `john`, "media type text/plain is not accepted"},
+		{"application/json", `{"name": "John"}`, "/name: must match ^[a-z]+$"},
+		{"application/json", `{"name": "john"} {}`, "invalid character after top-level value"},
+	} {
+		got := ""
+		if err := doc.ValidateBody(op, c.mediaType, []byte(c.body)); err != nil {
+			got = err.Error()
+		}
+		if got != c.want {
+			t.Errorf("ValidateBody(%s, %s) = %q, want %q", c.mediaType, c.body, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package openapi3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+// ValidationError is a value at Pointer, a JSON pointer, violating a
+// schema.
+type ValidationError struct {
+	Pointer string
+	Detail  string
+}
+
+func (e ValidationError) Error() string {
+	if e.Pointer == "" {
+		return e.Detail
+	}
+	return e.Pointer + ": " + e.Detail
+}
+
+// ValidationErrors are the violations of a value.
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// DecodeJSON decodes b keeping numbers as json.Number, as Validate
+// expects.
+func DecodeJSON(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, fmt.Errorf("invalid character after top-level value")
+	}
+	return v, nil
+}
+
+// Validate checks v, decoded by DecodeJSON, against s, whose references
+// are to the schemas of the components of d. It returns ValidationErrors
+// or nil.
+func (d *Document) Validate(s *spec.Schema, v interface{}) error {
+	var errs ValidationErrors
+	d.validate(s, v, "", &errs, 0)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// maxDepth bounds the references followed, against recursive schemas.
+const maxDepth = 64
+
+func (d *Document) resolve(s *spec.Schema) (*spec.Schema, error) {
+	for i := 0; s != nil && s.Ref.String() != ""; i++ {
+		ref := s.Ref.String()
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if name == ref || i == maxDepth {
+			return nil, fmt.Errorf("unresolvable reference %s", ref)
+		}
+		if s = d.Components.Schemas[name]; s == nil {
+			return nil, fmt.Errorf("unresolvable reference %s", ref)
+		}
+	}
+	return s, nil
+}
+
+func (d *Document) validate(s *spec.Schema, v interface{}, ptr string, errs *ValidationErrors, depth int) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Pointer: ptr, Detail: fmt.Sprintf(format, args...)})
+	}
+	s, err := d.resolve(s)
+	if err != nil || depth > maxDepth {
+		if err == nil {
+			err = fmt.Errorf("schema is nested too deeply")
+		}
+		fail("%v", err)
+		return
+	}
+	if s == nil {
+		return
+	}
+
+	if v == nil {
+		if len(s.Type) > 0 && !s.Nullable && !s.Type.Contains("null") {
+			fail("must not be null")
+		}
+		return
+	}
+	if len(s.Type) > 0 && !s.Type.Contains(jsonType(v)) && !(s.Type.Contains("number") && jsonType(v) == "integer") {
+		fail("must be of type %s", strings.Join(s.Type, " or "))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		fail("must be one of %s", formatEnum(s.Enum))
+	}
+
+	switch v := v.(type) {
+	case string:
+		d.validateString(s, v, fail)
+	case json.Number:
+		d.validateNumber(s, v, fail)
+	case []interface{}:
+		if s.MinItems != nil && int64(len(v)) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && int64(len(v)) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.UniqueItems && !unique(v) {
+			fail("items must be unique")
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			for i, item := range v {
+				d.validate(s.Items.Schema, item, ptr+"/"+strconv.Itoa(i), errs, depth+1)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, ValidationError{Pointer: ptr + "/" + escapePointer(name), Detail: "is required"})
+			}
+		}
+		if s.MinProperties != nil && int64(len(v)) < *s.MinProperties {
+			fail("must have at least %d properties", *s.MinProperties)
+		}
+		if s.MaxProperties != nil && int64(len(v)) > *s.MaxProperties {
+			fail("must have at most %d properties", *s.MaxProperties)
+		}
+		for name, value := range v {
+			p := ptr + "/" + escapePointer(name)
+			if prop, ok := s.Properties[name]; ok {
+				d.validate(&prop, value, p, errs, depth+1)
+				continue
+			}
+			if ap := s.AdditionalProperties; ap != nil {
+				if ap.Schema != nil {
+					d.validate(ap.Schema, value, p, errs, depth+1)
+				} else if !ap.Allows {
+					*errs = append(*errs, ValidationError{Pointer: p, Detail: "is not allowed"})
+				}
+			}
+		}
+	}
+
+	for i := range s.AllOf {
+		d.validate(&s.AllOf[i], v, ptr, errs, depth+1)
+	}
+	if len(s.AnyOf) > 0 && d.matches(s.AnyOf, v, depth) == 0 {
+		fail("must match a schema of anyOf")
+	}
+	if len(s.OneOf) > 0 && d.matches(s.OneOf, v, depth) != 1 {
+		fail("must match exactly one schema of oneOf")
+	}
+	if s.Not != nil && d.matches([]spec.Schema{*s.Not}, v, depth) == 1 {
+		fail("must not match the schema of not")
+	}
+}
+
+// matches returns how many of schemas v matches.
+func (d *Document) matches(schemas []spec.Schema, v interface{}, depth int) int {
+	n := 0
+	for i := range schemas {
+		var errs ValidationErrors
+		if d.validate(&schemas[i], v, "", &errs, depth+1); len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *Document) validateString(s *spec.Schema, v string, fail func(string, ...interface{})) {
+	n := int64(utf8.RuneCountInString(v))
+	if s.MinLength != nil && n < *s.MinLength {
+		fail("must be at least %d characters long", *s.MinLength)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		fail("must be at most %d characters long", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		re, err := compile(s.Pattern)
+		if err != nil {
+			fail("has an invalid pattern: %v", err)
+		} else if !re.MatchString(v) {
+			fail("must match %s", s.Pattern)
+		}
+	}
+	switch s.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			fail("must be a date-time of RFC 3339")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			fail("must be a full-date of RFC 3339")
+		}
+	}
+}
+
+func (d *Document) validateNumber(s *spec.Schema, v json.Number, fail func(string, ...interface{})) {
+	f, err := v.Float64()
+	if err != nil {
+		fail("must be a number")
+		return
+	}
+	if s.Minimum != nil && (f < *s.Minimum || s.ExclusiveMinimum && f == *s.Minimum) {
+		fail("must be %s %v", orEqual(">", !s.ExclusiveMinimum), *s.Minimum)
+	}
+	if s.Maximum != nil && (f > *s.Maximum || s.ExclusiveMaximum && f == *s.Maximum) {
+		fail("must be %s %v", orEqual("<", !s.ExclusiveMaximum), *s.Maximum)
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		if q := f / *s.MultipleOf; q != math.Trunc(q) {
+			fail("must be a multiple of %v", *s.MultipleOf)
+		}
+	}
+	switch s.Format {
+	case "int32":
+		if _, err := strconv.ParseInt(v.String(), 10, 32); err != nil {
+			fail("must be a 32 bit integer")
+		}
+	case "int64":
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err != nil {
+			fail("must be a 64 bit integer")
+		}
+	}
+}
+
+func orEqual(op string, inclusive bool) string {
+	if inclusive {
+		return op + "="
+	}
+	return op
+}
+
+// jsonType returns the JSON schema type of a value decoded by DecodeJSON.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, each := range enum {
+		if equalJSON(each, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalJSON compares JSON values regardless of how numbers are decoded.
+func equalJSON(a, b interface{}) bool {
+	ab, err1 := json.Marshal(a)
+	bb, err2 := json.Marshal(b)
+	if err1 != nil || err2 != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	x, err1 := DecodeJSON(ab)
+	y, err2 := DecodeJSON(bb)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	if xn, ok := x.(json.Number); ok {
+		yn, ok := y.(json.Number)
+		xf, _ := xn.Float64()
+		yf, _ := yn.Float64()
+		return ok && xf == yf
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func formatEnum(enum []interface{}) string {
+	list := make([]string, len(enum))
+	for i, each := range enum {
+		b, _ := json.Marshal(each)
+		list[i] = string(b)
+	}
+	return strings.Join(list, ", ")
+}
+
+func unique(items []interface{}) bool {
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			if equalJSON(items[i], items[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func escapePointer(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}
+
+var patterns sync.Map // string to *regexp.Regexp
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// ValidateResponse checks that op declares the status of a response and
+// that its body has the declared media type and, if that is JSON, the
+// declared schema.
+func (d *Document) ValidateResponse(op *Operation, status int, header http.Header, body []byte) error {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if resp, ok = op.Responses["default"]; !ok {
+			return fmt.Errorf("status %d is not declared", status)
+		}
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	if len(resp.Content) == 0 {
+		return fmt.Errorf("status %d declares no body", status)
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("Content-Type: %v", err)
+	}
+	content, ok := resp.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("status %d declares no %s body", status, mediaType)
+	}
+	if !isJSON(mediaType) || content.Schema == nil {
+		return nil
+	}
+	v, err := DecodeJSON(body)
+	if err != nil {
+		return fmt.Errorf("body: %v", err)
+	}
+	return d.Validate(content.Schema, v)
+}
+
+// isJSON reports whether mediaType is JSON.
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,106 @@
+package openapi3
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+func testDocument(t *testing.T) *Document {
+	var doc Document
+	if err := json.Unmarshal([]byte(`{
+		"openapi": "3.0.3",
+		"paths": {
+			"/users/{userID}": {"get": {"responses": {
+				"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}},
+				"204": {"description": "No Content"}
+			}}}
+		},
+		"components": {"schemas": {
+			"User": {"type": "object", "required": ["name"], "additionalProperties": false, "properties": {
+				"id": {"type": "integer", "format": "int64", "minimum": 1},
+				"name": {"type": "string", "maxLength": 4, "pattern": "^[a-z]+$"},
+				"role": {"type": "string", "enum": ["admin", "user"]},
+				"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+				"friend": {"$ref": "#/components/schemas/User"}
+			}}
+		}}
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+func TestValidate(t *testing.T) {
+	doc := testDocument(t)
+	user := &spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/components/schemas/User")}}
+
+	for body, want := range map[string]string{
+		`{"id": 1, "name": "john", "role": "admin", "tags": ["a", "b"], "friend": {"name": "jane"}}`: "",
+		`{"name": "john", "friend": {"id": 0}}`:                                                      "/friend/id: must be >= 1; /friend/name: is required",
+		`{"name": "John!"}`:                                                                          "/name: must be at most 4 characters long; /name: must match ^[a-z]+$",
+		`{"name": "john", "role": "root"}`:                                                           `/role: must be one of "admin", "user"`,
+		`{"name": "john", "tags": ["a", "a"]}`:                                                       "/tags: items must be unique",
+		`{"name": "john", "id": 1.5}`:                                                                "/id: must be of type integer",
+		`{"name": "john", "age": 3}`:                                                                 "/age: is not allowed",
+		`{"name": "john", "id": 9223372036854775808}`:                                                "/id: must be a 64 bit integer",
+		`[]`: "must be of type object",
+	} {
+		v, err := DecodeJSON([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if err := doc.Validate(user, v); err != nil {
+			got = err.Error()
+		}
+		// Properties are visited in map order, so compare the errors as a set.
+		if !sameErrors(got, want) {
+			t.Errorf("Validate(%s) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func sameErrors(a, b string) bool {
+	x, y := strings.Split(a, "; "), strings.Split(b, "; ")
+	if len(x) != len(y) {
+		return false
+	}
+	seen := map[string]int{}
+	for _, each := range x {
+		seen[each]++
+	}
+	for _, each := range y {
+		if seen[each]--; seen[each] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := testDocument(t)
+	op := doc.Operation("GET", "/users/{userID}")
+	json := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+	for _, c := range []struct {
+		status int
+		header http.Header
+		body   string
+		ok     bool
+	}{
+		{200, json, `{"name": "john"}`, true},
+		{204, http.Header{}, ``, true},
+		{200, json, `{"id": 1}`, false},
+		{404, json, `{"title": "Not Found"}`, false},
+		{204, json, `{}`, false},
+		{200, http.Header{"Content-Type": {"application/xml"}}, `<User/>`, false},
+	} {
+		err := doc.ValidateResponse(op, c.status, c.header, []byte(c.body))
+		if (err == nil) != c.ok {
+			t.Errorf("ValidateResponse(%d, %s) = %v", c.status, c.body, err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestAcceptsOpenAPI3(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                                 false,
+		"application/json":                 false,
+		"*/*":                              false,
+		"application/vnd.oai.openapi+json": true,
+		"application/vnd.oai.openapi+json;version=3.0":             true,
+		"application/vnd.oai.openapi+json;version=2.0":             false,
+		"application/json, application/vnd.oai.openapi+json;q=0.9": true,
+	} {
+		if got := acceptsOpenAPI3(accept); got != want {
+			t.Errorf("acceptsOpenAPI3(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/wiki/user-service/openapi3"
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+// maxBodySize bounds the request bodies OpenAPIValidator reads.
+const maxBodySize = 1 << 20
+
+// OpenAPIValidator checks requests before the handlers see them, and
+// responses after, against the operations of an OpenAPI document.
+type OpenAPIValidator struct {
+	doc       *openapi3.Document
+	requests  bool
+	responses bool
+}
+
+// NewOpenAPIValidator returns a validator rejecting the requests violating
+// doc if requests is set, and logging the responses violating doc if
+// responses is set. The latter costs a copy of every response, so it is
+// meant for development.
+func NewOpenAPIValidator(doc *openapi3.Document, requests, responses bool) *OpenAPIValidator {
+	return &OpenAPIValidator{doc: doc, requests: requests, responses: responses}
+}
+
+// attrValidator is the request attribute holding the *OpenAPIValidator of
+// the container.
+const attrValidator = "openapi.validator"
+
+// Filter is a container filter letting v validate the routes that use
+// validated.
+func (v *OpenAPIValidator) Filter(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	req.SetAttribute(attrValidator, v)
+	next(req, resp)
+}
+
+// validated validates the requests and responses of the route with the
+// OpenAPIValidator of the container, if it has one. Use it with
+// restful.RouteBuilder.Do after the functions adding authentication and
+// rate limits, so that they turn requests away before their bodies are
+// read.
+func validated(b *restful.RouteBuilder) {
+	b.Filter(traceFilter("openapi", func(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+		if v, ok := req.Attribute(attrValidator).(*OpenAPIValidator); ok {
+			v.validate(req, resp, next)
+		} else {
+			next(req, resp)
+		}
+	}))
+}
+
+// validate checks the request and response of the operation of the route
+// against the document.
+func (v *OpenAPIValidator) validate(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	op := v.doc.Operation(req.Request.Method, specPath(req.SelectedRoutePath()))
+	if op == nil {
+		next(req, resp)
+		return
+	}
+	if v.requests {
+		if p := v.checkRequest(op, req, resp); p != nil {
+			problem.Write(req, resp, p)
+			return
+		}
+	}
+	if !v.responses {
+		next(req, resp)
+		return
+	}
+
+	rec := &responseRecorder{ResponseWriter: resp.ResponseWriter}
+	resp.ResponseWriter = rec
+	next(req, resp)
+	resp.ResponseWriter = rec.ResponseWriter
+
+	status := resp.StatusCode()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if err := v.doc.ValidateResponse(op, status, rec.Header(), rec.body.Bytes()); err != nil && !rec.truncated {
+		log.Printf("response %s of %s %s violates the OpenAPI document: %v",
+			RequestIDOf(req), req.Request.Method, req.SelectedRoutePath(), err)
+	}
+}
+
+// checkRequest returns the problem of a request violating op, or nil. It
+// leaves the body for the handler to read again.
+func (v *OpenAPIValidator) checkRequest(op *openapi3.Operation, req *restful.Request, resp *restful.Response) *problem.Problem {
+	if err := v.doc.ValidateParameters(op, req.Request, req.PathParameters()); err != nil {
+		p := problem.New(http.StatusBadRequest, "Parameters violate the schema.")
+		for _, each := range err.(openapi3.ValidationErrors) {
+			p.WithField(parameterField(each.Pointer), each.Detail)
+		}
+		return p
+	}
+	if op.RequestBody == nil || req.Request.Body == nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(resp, req.Request.Body, maxBodySize))
+	req.Request.Body.Close()
+	if err != nil {
+		return problem.Decode(err)
+	}
+	req.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	mediaType := ""
+	if len(body) > 0 {
+		if mediaType, _, err = mime.ParseMediaType(req.Request.Header.Get("Content-Type")); err != nil {
+			return problem.New(http.StatusUnsupportedMediaType, "Content-Type is invalid.")
+		}
+	}
+	switch err := v.doc.ValidateBody(op, mediaType, body).(type) {
+	case nil:
+		return nil
+	case openapi3.ValidationErrors:
+		p := problem.New(http.StatusUnprocessableEntity, "Body violates constraints of the schema.")
+		for _, each := range err {
+			p.WithField(bodyField(each.Pointer), each.Detail)
+		}
+		return p
+	default:
+		if err == openapi3.ErrBodyRequired {
+			return problem.New(http.StatusBadRequest, "Body is required.")
+		}
+		if _, ok := op.RequestBody.Content[mediaType]; !ok {
+			return problem.Newf(http.StatusUnsupportedMediaType, "Body of type %s is not accepted.", mediaType)
+		}
+		if p := problem.Decode(err); p.Status != http.StatusInternalServerError {
+			return p
+		}
+		return problem.New(http.StatusBadRequest, "Body is not valid JSON.")
+	}
+}
+
+// parameterField returns the name of the parameter at ptr, /<in>/<name>.
+func parameterField(ptr string) string {
+	parts := strings.SplitN(strings.TrimPrefix(ptr, "/"), "/", 3)
+	if len(parts) < 2 {
+		return ptr
+	}
+	return unescapePointer(parts[1])
+}
+
+// bodyField returns the dotted name of the field at ptr, as package
+// validate names fields.
+func bodyField(ptr string) string {
+	if ptr == "" {
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(ptr, "/"), "/")
+	for i, part := range parts {
+		parts[i] = unescapePointer(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func unescapePointer(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
+
+// responseRecorder keeps a copy of the first maxBodySize bytes of a
+// response.
+type responseRecorder struct {
+	http.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if room := maxBodySize - r.body.Len(); len(b) > room {
+		r.body.Write(b[:room])
+		r.truncated = true
+	} else {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
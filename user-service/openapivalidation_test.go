@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+func TestOpenAPIValidator(t *testing.T) {
+	c := newContract(t)
+	c.container.Filter(NewOpenAPIValidator(c.doc, true, true).Filter)
+
+	tokens, err := c.auth.issueTokens("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := bearer(tokens.Token)
+	none := http.Header{}
+	for _, tt := range []struct {
+		method, path string
+		header       http.Header
+		body         string
+		status       int
+		field        string
+	}{
+		// The entity accessors would answer 400 for a string age.
+		{"POST", "/users", admin, `{"name": "john", "age": "old"}`, http.StatusUnprocessableEntity, "age"},
+		{"POST", "/users", admin, `{"name": "john", "age": 200}`, http.StatusUnprocessableEntity, "age"},
+		{"POST", "/login", none, `{"name": 1, "password": "admin"}`, http.StatusUnprocessableEntity, "name"},
+		{"GET", "/users?maxAge=-1", http.Header{"Authorization": {"Basic YWRtaW46YWRtaW4="}}, "", http.StatusBadRequest, "maxAge"},
+		// Authentication comes first.
+		{"POST", "/users", none, `{"name": "john", "age": "old"}`, http.StatusUnauthorized, ""},
+		{"POST", "/users", admin, `{"name": "john", "age": 30}`, http.StatusCreated, ""},
+	} {
+		rec := c.call(tt.method, tt.path, tt.header, tt.body, tt.status)
+		if tt.field == "" {
+			continue
+		}
+		var p problem.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		if len(p.Errors) == 0 || p.Errors[0].Field != tt.field {
+			t.Errorf("%s %s: errors = %v, want field %s", tt.method, tt.path, p.Errors, tt.field)
+		}
+	}
+}
+
+func TestOpenAPIValidatorResponses(t *testing.T) {
+	c := newContract(t)
+	c.container.Filter(NewOpenAPIValidator(c.doc, false, true).Filter)
+	// Break the documented response of the JWKS.
+	c.doc.Components.Schemas["main.JWKS"].Required = []string{"missing"}
+
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+	req := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	c.container.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(buf.String(), "violates the OpenAPI document") {
+		t.Errorf("GET = %d, logged %q; want 200 and the violation logged", rec.Code, buf.String())
+	}
+}
+
+func TestResponseRecorder(t *testing.T) {
+	rec := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+	rec.Write([]byte("abc"))
+	if rec.body.String() != "abc" || rec.truncated {
+		t.Fatalf("body = %q, truncated %v", rec.body.String(), rec.truncated)
+	}
+	rec.Write(make([]byte, maxBodySize))
+	if rec.body.Len() != maxBodySize || !rec.truncated {
+		t.Errorf("body of %d bytes, truncated %v; want %d, true", rec.body.Len(), rec.truncated, maxBodySize)
+	}
+	if n := rec.ResponseWriter.(*httptest.ResponseRecorder).Body.Len(); n != maxBodySize+3 {
+		t.Errorf("passed on %d bytes, want %d", n, maxBodySize+3)
+	}
+}
+
+func TestFields(t *testing.T) {
+	for ptr, want := range map[string]string{
+		"":           "",
+		"/age":       "age",
+		"/tags/0":    "tags.0",
+		"/a~1b/c~0d": "a/b.c~d",
+	} {
+		if got := bodyField(ptr); got != want {
+			t.Errorf("bodyField(%q) = %q, want %q", ptr, got, want)
+		}
+	}
+	for ptr, want := range map[string]string{
+		"/query/maxAge":    "maxAge",
+		"/header/If-Match": "If-Match",
+		"/path/a~1b":       "a/b",
+	} {
+		if got := parameterField(ptr); got != want {
+			t.Errorf("parameterField(%q) = %q, want %q", ptr, got, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch"
+)
+
+const (
+	MIME_MERGE_PATCH = "application/merge-patch+json" // RFC 7396
+	MIME_JSON_PATCH  = "application/json-patch+json"  // RFC 6902
+)
+
+var (
+	errUnsupportedPatch = errors.New("unsupported patch media type")
+	errPatchTestFailed  = errors.New("patch test operation failed")
+)
+
+// applyPatch applies patch of the given content type to the JSON encoding
+// of usr and returns the decoded result. Unknown members are an error.
+func applyPatch(usr User, contentType string, patch []byte) (User, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return usr, errUnsupportedPatch
+	}
+	doc, err := json.Marshal(usr)
+	if err != nil {
+		return usr, err
+	}
+
+	switch mediaType {
+	case MIME_MERGE_PATCH:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	case MIME_JSON_PATCH:
+		var p jsonpatch.Patch
+		if p, err = jsonpatch.DecodePatch(patch); err == nil {
+			doc, err = p.Apply(doc)
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				return usr, errPatchTestFailed
+			}
+		}
+	default:
+		return usr, errUnsupportedPatch
+	}
+	if err != nil {
+		return usr, err
+	}
+
+	// Decode into a zero User, so that removed members end up empty.
+	patched := User{}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patched); err != nil {
+		return usr, err
+	}
+	return patched, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestApplyPatch(t *testing.T) {
+	usr := User{ID: 1, Name: "john", Age: 21, Owner: "john"}
+
+	for _, tt := range []struct {
+		contentType string
+		patch       string
+		want        User
+		err         error
+	}{
+		{MIME_MERGE_PATCH, `{"age":22}`, User{ID: 1, Name: "john", Age: 22, Owner: "john"}, nil},
+		{MIME_MERGE_PATCH + "; charset=utf-8", `{"owner":null}`, User{ID: 1, Name: "john", Age: 21}, nil},
+		{MIME_JSON_PATCH, `[{"op":"replace","path":"/name","value":"jane"}]`, User{ID: 1, Name: "jane", Age: 21, Owner: "john"}, nil},
+		{MIME_JSON_PATCH, `[{"op":"test","path":"/age","value":30}]`, usr, errPatchTestFailed},
+		{"application/json", `{"age":22}`, usr, errUnsupportedPatch},
+	} {
+		got, err := applyPatch(usr, tt.contentType, []byte(tt.patch))
+		if got != tt.want || err != tt.err {
+			t.Errorf("%s %s: got %+v, %v; want %+v, %v", tt.contentType, tt.patch, got, err, tt.want, tt.err)
+		}
+	}
+
+	if _, err := applyPatch(usr, MIME_MERGE_PATCH, []byte(`{"email":"john@example.com"}`)); err == nil {
+		t.Error("unknown member accepted")
+	}
+}
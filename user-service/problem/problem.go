@@ -0,0 +1,136 @@
+// Package problem writes errors as RFC 7807 problem details.
+package problem
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/wiki/user-service/validate"
+)
+
+const MIME_PROBLEM_JSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. It is also an error, so
+// that functions can return the problem their caller should write.
+type Problem struct {
+	Type     string       `json:"type,omitempty" description:"URI reference identifying the problem type"`
+	Title    string       `json:"title" description:"short summary of the problem type"`
+	Status   int          `json:"status" description:"HTTP status code"`
+	Detail   string       `json:"detail,omitempty" description:"explanation of this occurrence of the problem"`
+	Instance string       `json:"instance,omitempty" description:"URI reference of this occurrence of the problem"`
+	Errors   []FieldError `json:"errors,omitempty" description:"problems of individual fields"`
+}
+
+// FieldError is the problem of a single field of a request.
+type FieldError struct {
+	Field  string `json:"field" description:"JSON name of the field"`
+	Detail string `json:"detail" description:"what is wrong with the field"`
+}
+
+// New returns a problem of the generic "about:blank" type, titled by the
+// status text.
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func Newf(status int, format string, a ...interface{}) *Problem {
+	return New(status, fmt.Sprintf(format, a...))
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// WithType sets the type URI and title of a problem type of our own.
+func (p *Problem) WithType(uri, title string) *Problem {
+	p.Type, p.Title = uri, title
+	return p
+}
+
+// WithField adds the problem of a field.
+func (p *Problem) WithField(field, detail string) *Problem {
+	p.Errors = append(p.Errors, FieldError{Field: field, Detail: detail})
+	return p
+}
+
+// Write writes p as the response to req.
+func Write(req *restful.Request, resp *restful.Response, p *Problem) {
+	if p.Instance == "" && req != nil {
+		p.Instance = req.Request.URL.RequestURI()
+	}
+	resp.WriteHeaderAndJson(p.Status, p, MIME_PROBLEM_JSON)
+}
+
+// WriteError writes err as the response to req. A *Problem is written as
+// is; any other error is an internal server error whose message is not
+// disclosed.
+func WriteError(req *restful.Request, resp *restful.Response, err error) {
+	p, ok := err.(*Problem)
+	if !ok {
+		p = New(http.StatusInternalServerError, "")
+	}
+	Write(req, resp, p)
+}
+
+// Decode returns the problem of an error of restful.Request.ReadEntity.
+// Bodies that cannot be decoded are bad requests, and those violating the
+// constraints checked by package validate are unprocessable; only
+// unexpected errors are internal errors.
+func Decode(err error) *Problem {
+	switch err := err.(type) {
+	case *Problem:
+		return err
+	case *json.SyntaxError:
+		return Newf(http.StatusBadRequest, "Body is not valid JSON at offset %d.", err.Offset)
+	case *json.UnmarshalTypeError:
+		p := New(http.StatusBadRequest, "Body does not match the schema.")
+		if err.Field != "" {
+			p.WithField(err.Field, "expected "+err.Type.String()+", got "+err.Value)
+		}
+		return p
+	case *xml.SyntaxError:
+		return Newf(http.StatusBadRequest, "Body is not valid XML at line %d.", err.Line)
+	case *strconv.NumError:
+		// encoding/xml reports values that are no numbers or booleans
+		// this way.
+		return New(http.StatusBadRequest, "Body does not match the schema.")
+	case *http.MaxBytesError:
+		return Newf(http.StatusRequestEntityTooLarge, "Body is larger than %d bytes.", err.Limit)
+	case validate.Errors:
+		p := New(http.StatusUnprocessableEntity, "Body violates constraints of the schema.")
+		for _, each := range err {
+			p.WithField(each.Field, each.Detail)
+		}
+		return p
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return New(http.StatusBadRequest, "Body is empty or truncated.")
+	}
+	// encoding/json reports unknown fields and the like as plain errors.
+	if msg := err.Error(); strings.HasPrefix(msg, "json: ") {
+		return New(http.StatusBadRequest, msg)
+	}
+	return New(http.StatusInternalServerError, "")
+}
+
+// Declare documents the problems any route may respond with: bad requests
+// and internal errors. Use it with restful.RouteBuilder.Do before the
+// Returns of the route, which may then describe these statuses better.
+func Declare(b *restful.RouteBuilder) {
+	b.Returns(http.StatusBadRequest, "Bad Request", Problem{}).
+		Returns(http.StatusInternalServerError, "Internal Server Error", Problem{})
+}
@@ -0,0 +1,41 @@
+package problem
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/tangblue/wiki/user-service/validate"
+)
+
+func TestDecode(t *testing.T) {
+	var v struct{ Age int }
+	typeErr := json.Unmarshal([]byte(`{"Age":"old"}`), &v)
+	syntaxErr := json.Unmarshal([]byte(`{"Age":`), &v)
+	xmlTypeErr := xml.Unmarshal([]byte(`<user><Age>abc</Age></user>`), &v)
+
+	for _, tt := range []struct {
+		err    error
+		status int
+	}{
+		{syntaxErr, http.StatusBadRequest},
+		{typeErr, http.StatusBadRequest},
+		{xmlTypeErr, http.StatusBadRequest},
+		{io.EOF, http.StatusBadRequest},
+		{errors.New(`json: unknown field "email"`), http.StatusBadRequest},
+		{New(http.StatusUnsupportedMediaType, ""), http.StatusUnsupportedMediaType},
+		{validate.Errors{{Field: "age", Detail: "must be at most 150"}}, http.StatusUnprocessableEntity},
+		{errors.New("connection reset"), http.StatusInternalServerError},
+	} {
+		if p := Decode(tt.err); p.Status != tt.status {
+			t.Errorf("Decode(%v).Status = %d, want %d", tt.err, p.Status, tt.status)
+		}
+	}
+
+	if p := Decode(typeErr); len(p.Errors) != 1 || p.Errors[0].Field != "Age" {
+		t.Errorf("Decode(%v).Errors = %v", typeErr, p.Errors)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+// AuthLimits throttles authentication. A zero rate or threshold disables
+// the limit.
+type AuthLimits struct {
+	// IPRate and IPBurst limit the authentication requests of a client IP.
+	IPRate  float64 `yaml:"ipRate" env:"AUTH_IP_RATE"`
+	IPBurst int     `yaml:"ipBurst" env:"AUTH_IP_BURST"`
+	// UserRate and UserBurst limit the authentication attempts for a name.
+	UserRate  float64 `yaml:"userRate" env:"AUTH_USER_RATE"`
+	UserBurst int     `yaml:"userBurst" env:"AUTH_USER_BURST"`
+	// After LockoutThreshold failures in a row, a name is locked for
+	// LockoutBase, doubled for each further failure up to LockoutMax.
+	LockoutThreshold int           `yaml:"lockoutThreshold" env:"LOCKOUT_THRESHOLD"`
+	LockoutBase      time.Duration `yaml:"lockoutBase" env:"LOCKOUT_BASE"`
+	LockoutMax       time.Duration `yaml:"lockoutMax" env:"LOCKOUT_MAX"`
+}
+
+// RateLimiter is a token bucket per key. A nil *RateLimiter allows
+// everything.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rate requests per second and
+// bursts of burst requests per key, or nil if rate is not positive.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), buckets: map[string]*bucket{}}
+}
+
+// Allow takes a token of key. If there is none, it returns false and how
+// long until there is.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		l.prune(now)
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune drops the buckets that have filled up again, which are the same
+// as new ones. The caller must hold l.mu.
+func (l *RateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Filter rejects requests beyond the limit of the key of the request.
+// Requests with an empty key are not limited.
+func (l *RateLimiter) Filter(key func(*restful.Request) string) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+		if k := key(req); k != "" {
+			if ok, retry := l.Allow(k); !ok {
+				writeTooManyRequests(req, resp, retry, "Too many requests from this client.")
+				return
+			}
+		}
+		next(req, resp)
+	}
+}
+
+// rateLimited limits the requests of each client IP to the route with the
+// IP limit of a. Use it with restful.RouteBuilder.Do.
+func (a *Auth) rateLimited(b *restful.RouteBuilder) {
+	b.Filter(traceFilter("rateLimited", a.ipLimiter.Filter(clientIP))).
+		Returns(http.StatusTooManyRequests, "Too Many Requests", problem.Problem{})
+}
+
+// clientIP returns the IP address of the peer of req. Proxies are not
+// trusted to tell the address of their client.
+func clientIP(req *restful.Request) string {
+	host, _, err := net.SplitHostPort(req.Request.RemoteAddr)
+	if err != nil {
+		return req.Request.RemoteAddr
+	}
+	return host
+}
+
+func writeTooManyRequests(req *restful.Request, resp *restful.Response, retry time.Duration, detail string) {
+	resp.AddHeader("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	problem.Write(req, resp, problem.New(http.StatusTooManyRequests, detail))
+}
+
+// Lockout locks names out after repeated authentication failures. A nil
+// *Lockout never locks.
+type Lockout struct {
+	mu        sync.Mutex
+	threshold int
+	base, max time.Duration
+	failures  map[string]*lockState
+}
+
+type lockState struct {
+	count int
+	until time.Time
+}
+
+// NewLockout returns a lockout after threshold failures, or nil if
+// threshold is not positive.
+func NewLockout(threshold int, base, max time.Duration) *Lockout {
+	if threshold <= 0 {
+		return nil
+	}
+	return &Lockout{threshold: threshold, base: base, max: max, failures: map[string]*lockState{}}
+}
+
+// Locked returns how long name is still locked out.
+func (l *Lockout) Locked(name string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.failures[name]; ok {
+		if d := time.Until(s.until); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Fail counts a failure of name and returns how long it is now locked out.
+func (l *Lockout) Fail(name string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	s, ok := l.failures[name]
+	if !ok || now.Sub(s.until) > l.max {
+		l.prune(now)
+		s = &lockState{}
+		l.failures[name] = s
+	}
+	s.count++
+	if s.count < l.threshold {
+		s.until = now
+		return 0
+	}
+	// Doubling past max would overflow after enough failures.
+	d := l.max
+	if n := s.count - l.threshold; n < 62 && l.base <= l.max>>uint(n) {
+		d = l.base << uint(n)
+	}
+	s.until = now.Add(d)
+	return d
+}
+
+// prune forgets the names that last failed or were unlocked longer than
+// the longest lockout ago. The caller must hold l.mu.
+func (l *Lockout) prune(now time.Time) {
+	for name, s := range l.failures {
+		if now.Sub(s.until) > l.max {
+			delete(l.failures, name)
+		}
+	}
+}
+
+// Reset forgets the failures of name after it authenticated.
+func (l *Lockout) Reset(name string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, name)
+}
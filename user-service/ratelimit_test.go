@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(1, 3)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("10.0.0.1"); !ok {
+			t.Fatalf("request %d of the burst was refused", i+1)
+		}
+	}
+	ok, retry := l.Allow("10.0.0.1")
+	if ok || retry <= 0 || retry > time.Second {
+		t.Errorf("Allow after burst = %v, %v; want false, at most 1s", ok, retry)
+	}
+	if ok, _ := l.Allow("10.0.0.2"); !ok {
+		t.Error("another key was refused")
+	}
+
+	var unlimited *RateLimiter
+	if ok, _ := unlimited.Allow("10.0.0.1"); !ok {
+		t.Error("nil limiter refused")
+	}
+}
+
+func TestLockout(t *testing.T) {
+	l := NewLockout(3, time.Minute, 5*time.Minute)
+	for i := 0; i < 2; i++ {
+		if d := l.Fail("john"); d != 0 {
+			t.Fatalf("failure %d locked out for %v", i+1, d)
+		}
+	}
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		if d := l.Fail("john"); d != want {
+			t.Errorf("Fail = %v, want %v", d, want)
+		}
+	}
+	if d := l.Locked("john"); d <= 4*time.Minute {
+		t.Errorf("Locked = %v, want about 5m", d)
+	}
+	if d := l.Locked("jane"); d != 0 {
+		t.Errorf("Locked of another name = %v", d)
+	}
+
+	l.Reset("john")
+	if d := l.Locked("john"); d != 0 {
+		t.Errorf("Locked after Reset = %v", d)
+	}
+
+	// Shifted 41 times, the base overflows to about 37 minutes.
+	l = NewLockout(1, 1<<23+1, time.Hour)
+	for i := 1; i <= 64; i++ {
+		if d := l.Fail("john"); i > 20 && d != time.Hour {
+			t.Fatalf("Fail after %d failures = %v, want 1h", i, d)
+		}
+	}
+}
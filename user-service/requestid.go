@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/tangblue/goapi/restful"
+)
+
+// attrRequestID is the request attribute holding the ID of the request.
+const attrRequestID = "requestID"
+
+// maxRequestIDLength bounds the X-Request-ID taken from clients.
+const maxRequestIDLength = 128
+
+// requestIDFilter identifies every request by the X-Request-ID header of
+// the client, or by a new random ID if it has none, and echoes the ID in
+// the response.
+func requestIDFilter(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	id := req.Request.Header.Get("X-Request-ID")
+	if id == "" || len(id) > maxRequestIDLength {
+		id, _ = newTokenID()
+	}
+	req.SetAttribute(attrRequestID, id)
+	resp.AddHeader("X-Request-ID", id)
+	next(req, resp)
+}
+
+// RequestIDOf returns the ID requestIDFilter gave the request.
+func RequestIDOf(req *restful.Request) string {
+	id, _ := req.Attribute(attrRequestID).(string)
+	return id
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Revocations is the list of revoked token IDs (jti). An entry is kept
+// until the token it revokes would have expired anyway. The list lives in
+// memory only: it is lost on restart, and instances do not share it, so
+// revoked tokens work again there until they expire.
+type Revocations struct {
+	mu  sync.Mutex
+	jti map[string]time.Time
+}
+
+func NewRevocations() *Revocations {
+	return &Revocations{jti: map[string]time.Time{}}
+}
+
+func (r *Revocations) Revoke(jti string, expiresAt time.Time) {
+	r.RevokeIfNew(jti, expiresAt)
+}
+
+// RevokeIfNew revokes jti and reports whether it was not revoked before.
+func (r *Revocations) RevokeIfNew(jti string, expiresAt time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for each, exp := range r.jti {
+		if now.After(exp) {
+			delete(r.jti, each)
+		}
+	}
+	if _, ok := r.jti[jti]; ok {
+		return false
+	}
+	r.jti[jti] = expiresAt
+	return true
+}
+
+func (r *Revocations) IsRevoked(jti string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.jti[jti]
+	return ok
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/spec"
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+
+	ScopeUsersRead   = "users:read"
+	ScopeUsersWrite  = "users:write"
+	ScopeUsersDelete = "users:delete"
+	ScopeAuditRead   = "audit:read"
+)
+
+// roleScopes are the scopes granted to each role. Users may change and
+// delete only the records they own, see mayModify.
+var roleScopes = map[string][]string{
+	RoleAdmin: {ScopeUsersRead, ScopeUsersWrite, ScopeUsersDelete, ScopeAuditRead},
+	RoleUser:  {ScopeUsersRead, ScopeUsersWrite, ScopeUsersDelete},
+}
+
+var scopeDescriptions = map[string]string{
+	ScopeUsersRead:   "read users",
+	ScopeUsersWrite:  "create and update users",
+	ScopeUsersDelete: "delete users",
+	ScopeAuditRead:   "read the audit log",
+}
+
+// scopesOf returns the space separated scopes granted to roles.
+func scopesOf(roles []string) string {
+	set := map[string]bool{}
+	for _, role := range roles {
+		for _, scope := range roleScopes[role] {
+			set[scope] = true
+		}
+	}
+	scopes := make([]string, 0, len(set))
+	for scope := range set {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return strings.Join(scopes, " ")
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	for _, each := range strings.Fields(c.Scope) {
+		if each == scope {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// Route metadata from which addSecurityRequirements builds the
+	// security requirements of the OpenAPI operations.
+	KeySecurityScheme = "security.scheme"
+	KeySecurityScopes = "security.scopes"
+
+	securitySchemeBasic = "basic"
+	securitySchemeJWT   = "jwt"
+	// securitySchemeClientCert is mutual TLS, which Swagger 2.0 and
+	// OpenAPI 3.0 cannot declare, so operations describe it instead.
+	securitySchemeClientCert = "clientCert"
+)
+
+// attrClaims is the request attribute holding the *Claims of the
+// authenticated caller.
+const attrClaims = "claims"
+
+// ClaimsOf returns the claims of the caller verified by basicAuthenticate,
+// JWTAuthenticate or clientCertAuthenticate, or nil if the route does not authenticate.
+func ClaimsOf(req *restful.Request) *Claims {
+	claims, _ := req.Attribute(attrClaims).(*Claims)
+	return claims
+}
+
+func (c *Claims) IsAdmin() bool {
+	for _, role := range c.Roles {
+		if role == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeFilter rejects requests whose caller lacks any of scopes. It must
+// run after an authenticating filter.
+func (a *Auth) scopeFilter(scopes []string) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+		claims := ClaimsOf(req)
+		if claims == nil {
+			problem.Write(req, resp, problem.New(http.StatusUnauthorized, "Authentication is required."))
+			return
+		}
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				problem.Write(req, resp, problem.New(http.StatusForbidden, "Caller is not allowed to do this."))
+				return
+			}
+		}
+		next(req, resp)
+	}
+}
+
+// basicAuth authenticates the route with basicAuthenticate. Use it with
+// restful.RouteBuilder.Do.
+func (a *Auth) basicAuth(b *restful.RouteBuilder) {
+	a.rateLimited(b)
+	b.Filter(traceFilter("basicAuthenticate", a.basicAuthenticate)).
+		Metadata(KeySecurityScheme, securitySchemeBasic).
+		Returns(http.StatusUnauthorized, "Not Authorized", problem.Problem{})
+}
+
+// jwtAuth authenticates the route with JWTAuthenticate. Use it with
+// restful.RouteBuilder.Do.
+func (a *Auth) jwtAuth(b *restful.RouteBuilder) {
+	b.Filter(traceFilter("JWTAuthenticate", a.JWTAuthenticate)).
+		Param(a.hpAuthorization).
+		Metadata(KeySecurityScheme, securitySchemeJWT).
+		Returns(http.StatusUnauthorized, "Not Authorized", problem.Problem{})
+}
+
+// requireScopes returns the restful.RouteBuilder.Do function limiting the
+// route to callers with scopes. It must follow basicAuth, jwtAuth or
+// clientCertAuth.
+func (a *Auth) requireScopes(scopes ...string) func(*restful.RouteBuilder) {
+	return func(b *restful.RouteBuilder) {
+		b.Filter(traceFilter("scopeFilter", a.scopeFilter(scopes))).
+			Metadata(KeySecurityScopes, scopes).
+			Returns(http.StatusForbidden, "Forbidden", problem.Problem{})
+	}
+}
+
+// addSecurityDefinitions declares the security schemes of swo. The JWTs
+// of POST /login are sent as bearer tokens, which Swagger 2.0 can only
+// declare as an API key in the Authorization header.
+func addSecurityDefinitions(swo *spec.Swagger) {
+	scopes := make([]string, 0, len(scopeDescriptions))
+	for scope, desc := range scopeDescriptions {
+		scopes = append(scopes, scope+": "+desc)
+	}
+	sort.Strings(scopes)
+	jwt := spec.APIKeyAuth("Authorization", "header")
+	jwt.Description = "JWT from POST /login as \"Bearer <token>\". Scopes:\n\n- " + strings.Join(scopes, "\n- ")
+	swo.SecurityDefinitions = spec.SecurityDefinitions{
+		securitySchemeBasic: spec.BasicAuth(),
+		securitySchemeJWT:   jwt,
+	}
+}
+
+// addSecurityRequirements sets the security of the operations of swo from
+// the KeySecurityScheme and KeySecurityScopes metadata of the routes. No
+// scheme is OAuth2, so the scopes go to the descriptions.
+func addSecurityRequirements(swo *spec.Swagger, wss []*restful.WebService) {
+	if swo.Paths == nil {
+		return
+	}
+	for _, ws := range wss {
+		for _, route := range ws.Routes() {
+			scheme, _ := route.Metadata[KeySecurityScheme].(string)
+			if scheme == "" {
+				continue
+			}
+			op := routeOperation(swo, route)
+			if op == nil {
+				continue
+			}
+			if scheme == securitySchemeClientCert {
+				op.Description = strings.TrimSpace(op.Description + "\n\nRequires a TLS client certificate.")
+			} else {
+				op.Security = append(op.Security, map[string][]string{scheme: {}})
+			}
+			if scopes, _ := route.Metadata[KeySecurityScopes].([]string); len(scopes) > 0 {
+				op.Description = strings.TrimSpace(op.Description + "\n\nRequired scopes: " + strings.Join(scopes, ", "))
+			}
+		}
+	}
+}
+
+// selectedRoutePath returns the path of the route that matched req, or ""
+// if none did: goapi's SelectedRoutePath panics on requests without a
+// route, which container filters also see.
+func selectedRoutePath(req *restful.Request) (path string) {
+	defer func() { recover() }()
+	return req.SelectedRoutePath()
+}
+
+// specPath returns the path of the document for the path of a route, the
+// way restfulspec writes it: without empty segments, and with the regular
+// expressions of the parameters removed.
+func specPath(routePath string) string {
+	path := ""
+	for _, segment := range strings.Split(routePath, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") {
+			if i := strings.Index(segment, ":"); i > 0 {
+				segment = segment[:i] + "}"
+			}
+		}
+		path += "/" + segment
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// routeOperation returns the operation of swo documenting route, or nil.
+func routeOperation(swo *spec.Swagger, route restful.Route) *spec.Operation {
+	return operationOf(swo.Paths.Paths[specPath(route.Path)], route.Method)
+}
+
+func operationOf(item spec.PathItem, method string) *spec.Operation {
+	switch method {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodPut:
+		return item.Put
+	case http.MethodPost:
+		return item.Post
+	case http.MethodDelete:
+		return item.Delete
+	case http.MethodPatch:
+		return item.Patch
+	case http.MethodHead:
+		return item.Head
+	case http.MethodOptions:
+		return item.Options
+	}
+	return nil
+}
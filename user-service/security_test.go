@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restfulspec"
+)
+
+// scopeHeader authenticates callers with the scope of their X-Scope header.
+func scopeHeader(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	if scope, ok := req.Request.Header["X-Scope"]; ok {
+		req.SetAttribute(attrClaims, &Claims{Scope: scope[0]})
+	}
+	next(req, resp)
+}
+
+func noContent(req *restful.Request, resp *restful.Response) {
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func TestScopeFilter(t *testing.T) {
+	a := &Auth{}
+	ws := new(restful.WebService)
+	ws.Path("/t").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("").Handler(noContent).Filter(scopeHeader).
+		Do(a.requireScopes(ScopeUsersRead, ScopeUsersWrite)))
+	container := restful.NewContainer()
+	container.Add(ws)
+
+	for _, c := range []struct {
+		scope []string
+		want  int
+	}{
+		{nil, http.StatusUnauthorized},
+		{[]string{""}, http.StatusForbidden},
+		{[]string{ScopeUsersRead}, http.StatusForbidden},
+		{[]string{ScopeUsersRead + " " + ScopeUsersWrite}, http.StatusNoContent},
+		{[]string{ScopeUsersWrite + " " + ScopeAuditRead + " " + ScopeUsersRead}, http.StatusNoContent},
+	} {
+		req := httptest.NewRequest("GET", "/t", nil)
+		if c.scope != nil {
+			req.Header["X-Scope"] = c.scope
+		}
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("scope %q: GET = %d, want %d", c.scope, rec.Code, c.want)
+		}
+	}
+}
+
+func TestAddSecurityRequirements(t *testing.T) {
+	a := NewAuth(nil, nil, NewAuditLog(), time.Minute, time.Hour, AuthLimits{})
+	ws := new(restful.WebService)
+	ws.Path("/t").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("").Handler(noContent).Do(a.jwtAuth, a.requireScopes(ScopeUsersRead)))
+	ws.Route(ws.GET("/jwt").Handler(noContent).Do(a.jwtAuth, a.requireScopes(ScopeUsersRead, ScopeUsersWrite)))
+	ws.Route(ws.GET("/basic").Handler(noContent).Do(a.basicAuth))
+	ws.Route(ws.GET("/cert").Handler(noContent).Do(a.clientCertAuth, a.requireScopes(ScopeAuditRead)))
+	ws.Route(ws.GET("/open").Handler(noContent))
+	wss := []*restful.WebService{ws}
+	swo := restfulspec.BuildSwagger(restfulspec.Config{WebServices: wss})
+	addSecurityDefinitions(swo)
+	addSecurityRequirements(swo, wss)
+
+	if s := swo.SecurityDefinitions[securitySchemeJWT]; s == nil || s.Type != "apiKey" || s.In != "header" || s.Name != "Authorization" {
+		t.Errorf("jwt scheme = %+v", s)
+	}
+	for path, want := range map[string]struct {
+		scheme string
+		desc   []string
+	}{
+		"/t":       {securitySchemeJWT, []string{"Required scopes: users:read"}},
+		"/t/jwt":   {securitySchemeJWT, []string{"Required scopes: users:read, users:write"}},
+		"/t/basic": {securitySchemeBasic, nil},
+		"/t/cert":  {"", []string{"Requires a TLS client certificate.", "Required scopes: audit:read"}},
+		"/t/open":  {"", nil},
+	} {
+		op := swo.Paths.Paths[path].Get
+		if op == nil {
+			t.Errorf("%s is not documented", path)
+			continue
+		}
+		var schemes []string
+		for _, requirement := range op.Security {
+			for name, scopes := range requirement {
+				schemes = append(schemes, name)
+				if len(scopes) != 0 {
+					t.Errorf("%s: requirement %s lists scopes %v", path, name, scopes)
+				}
+			}
+		}
+		if strings.Join(schemes, ",") != want.scheme {
+			t.Errorf("%s: security schemes = %v, want %q", path, schemes, want.scheme)
+		}
+		for _, desc := range want.desc {
+			if !strings.Contains(op.Description, desc) {
+				t.Errorf("%s: description %q lacks %q", path, op.Description, desc)
+			}
+		}
+	}
+}
+
+func TestSpecPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/users/":                   "/users",
+		"/users":                    "/users",
+		"/":                         "/",
+		"/users/{userID}":           "/users/{userID}",
+		"/users/{userID:[0-9]+}/x/": "/users/{userID}/x",
+		"//login//refresh":          "/login/refresh",
+	} {
+		if got := specPath(path); got != want {
+			t.Errorf("specPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
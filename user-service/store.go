@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUserExists      = errors.New("user already exists")
+	ErrVersionMismatch = errors.New("user version mismatch")
+	ErrNoFreeID        = errors.New("no user ID is left")
+)
+
+// Version is bumped on every change of a user. It is used as the ETag
+// of the user resource.
+type Version uint64
+
+// AnyVersion disables the version check of Put and Delete.
+const AnyVersion Version = 0
+
+// UserStore persists users for UserResource.
+//
+// Create stores a new user. It allocates the next free ID if usr.ID is 0
+// and fails with ErrUserExists if usr.ID is taken. Put and Delete fail
+// with ErrVersionMismatch unless match is AnyVersion or the current
+// version of the user.
+type UserStore interface {
+	Get(id UID) (User, Version, error)
+	List() ([]User, error)
+	Create(usr User) (User, Version, error)
+	Put(usr User, match Version) (Version, error)
+	Delete(id UID, match Version) error
+}
+
+// NewUserStore creates the store named by kind: "memory" or "file".
+// path is the log file used by the file store.
+func NewUserStore(kind, path string) (UserStore, error) {
+	switch kind {
+	case "memory":
+		return NewMemUserStore(), nil
+	case "file":
+		s, err := OpenFileUserStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown user store %q", kind)
+	}
+}
+
+type userRecord struct {
+	User    User
+	Version Version
+}
+
+type memUserStore struct {
+	mu    sync.RWMutex
+	users map[UID]userRecord
+	// seq is the last version handed out. Versions are never reused, not
+	// even after a user is deleted and created again.
+	seq Version
+	// lastID is the highest ID ever stored. IDs are allocated above it.
+	lastID UID
+}
+
+func NewMemUserStore() UserStore {
+	return &memUserStore{users: map[UID]userRecord{}}
+}
+
+func (s *memUserStore) Get(id UID) (User, Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.users[id]
+	if !ok {
+		return User{}, AnyVersion, ErrUserNotFound
+	}
+	return rec.User, rec.Version, nil
+}
+
+func (s *memUserStore) List() ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]User, 0, len(s.users))
+	for _, each := range s.users {
+		list = append(list, each.User)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// check returns the current version of id. The caller must hold s.mu.
+func (s *memUserStore) check(id UID, match Version) (Version, error) {
+	rec, ok := s.users[id]
+	if match != AnyVersion && (!ok || rec.Version != match) {
+		return rec.Version, ErrVersionMismatch
+	}
+	return rec.Version, nil
+}
+
+// allocate assigns the next free ID to usr if it has none. The caller must
+// hold s.mu.
+func (s *memUserStore) allocate(usr *User) error {
+	if usr.ID == 0 {
+		if s.lastID == math.MaxInt64 {
+			return ErrNoFreeID
+		}
+		usr.ID = s.lastID + 1
+	} else if _, ok := s.users[usr.ID]; ok {
+		return ErrUserExists
+	}
+	return nil
+}
+
+// set stores usr with version v. The caller must hold s.mu.
+func (s *memUserStore) set(usr User, v Version) {
+	s.users[usr.ID] = userRecord{User: usr, Version: v}
+	if v > s.seq {
+		s.seq = v
+	}
+	if usr.ID > s.lastID {
+		s.lastID = usr.ID
+	}
+}
+
+func (s *memUserStore) Create(usr User) (User, Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.allocate(&usr); err != nil {
+		return usr, AnyVersion, err
+	}
+	s.set(usr, s.seq+1)
+	return usr, s.seq, nil
+}
+
+func (s *memUserStore) Put(usr User, match Version) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cur, err := s.check(usr.ID, match); err != nil {
+		return cur, err
+	}
+	s.set(usr, s.seq+1)
+	return s.seq, nil
+}
+
+func (s *memUserStore) Delete(id UID, match Version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return ErrUserNotFound
+	}
+	if _, err := s.check(id, match); err != nil {
+		return err
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// fileUserStore keeps users in memory and appends every change to a log
+// file, which is replayed on open.
+type fileUserStore struct {
+	memUserStore
+	f *os.File
+}
+
+type userLogEntry struct {
+	Op      string  `json:"op"`
+	ID      UID     `json:"id"`
+	Version Version `json:"version,omitempty"`
+	User    *User   `json:"user,omitempty"`
+}
+
+func OpenFileUserStore(path string) (*fileUserStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &fileUserStore{
+		memUserStore: memUserStore{users: map[UID]userRecord{}},
+		f:            f,
+	}
+	if err := s.replay(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+func (s *fileUserStore) replay() error {
+	r := bufio.NewReader(s.f)
+	var end int64 // offset after the last complete record
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// A crash cut the last record short. Its write never
+				// succeeded, so drop it.
+				log.Printf("%s: dropping the partial record at offset %d", s.f.Name(), end)
+				if err := s.f.Truncate(end); err != nil {
+					return err
+				}
+			}
+			break
+		} else if err != nil {
+			return err
+		}
+		e := userLogEntry{}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("record at offset %d: %v", end, err)
+		}
+		end += int64(len(line))
+		switch e.Op {
+		case "put":
+			if e.User == nil {
+				return fmt.Errorf("put %d without user", e.ID)
+			}
+			s.set(*e.User, e.Version)
+		case "delete":
+			delete(s.users, e.ID)
+		default:
+			return fmt.Errorf("unknown op %q", e.Op)
+		}
+	}
+	_, err := s.f.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (s *fileUserStore) append(e userLogEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *fileUserStore) Create(usr User) (User, Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.allocate(&usr); err != nil {
+		return usr, AnyVersion, err
+	}
+	if err := s.append(userLogEntry{Op: "put", ID: usr.ID, Version: s.seq + 1, User: &usr}); err != nil {
+		return usr, AnyVersion, err
+	}
+	s.set(usr, s.seq+1)
+	return usr, s.seq, nil
+}
+
+func (s *fileUserStore) Put(usr User, match Version) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, err := s.check(usr.ID, match)
+	if err != nil {
+		return cur, err
+	}
+	if err := s.append(userLogEntry{Op: "put", ID: usr.ID, Version: s.seq + 1, User: &usr}); err != nil {
+		return cur, err
+	}
+	s.set(usr, s.seq+1)
+	return s.seq, nil
+}
+
+func (s *fileUserStore) Delete(id UID, match Version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return ErrUserNotFound
+	}
+	if _, err := s.check(id, match); err != nil {
+		return err
+	}
+	if err := s.append(userLogEntry{Op: "delete", ID: id}); err != nil {
+		return err
+	}
+	delete(s.users, id)
+	return nil
+}
+
+func (s *fileUserStore) Close() error {
+	return s.f.Close()
+}
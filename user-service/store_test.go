@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testUserStore(t *testing.T, s UserStore) {
+	if _, _, err := s.Get(1); err != ErrUserNotFound {
+		t.Fatalf("Get on empty store: got %v, want %v", err, ErrUserNotFound)
+	}
+
+	john := User{ID: 1, Name: "john", Age: 21}
+	jane := User{ID: 2, Name: "jane", Age: 22}
+	for _, usr := range []User{jane, john} {
+		if _, err := s.Put(usr, AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usr, v, err := s.Get(1)
+	if err != nil || usr != john {
+		t.Fatalf("Get(1) = %v, %v; want %v", usr, err, john)
+	}
+	if list, err := s.List(); err != nil || !reflect.DeepEqual(list, []User{john, jane}) {
+		t.Fatalf("List() = %v, %v", list, err)
+	}
+
+	john.Age++
+	nv, err := s.Put(john, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nv == v {
+		t.Fatalf("Put kept version %v", v)
+	}
+	if _, err := s.Put(john, v); err != ErrVersionMismatch {
+		t.Fatalf("Put with stale version: got %v, want %v", err, ErrVersionMismatch)
+	}
+	if err := s.Delete(1, v); err != ErrVersionMismatch {
+		t.Fatalf("Delete with stale version: got %v, want %v", err, ErrVersionMismatch)
+	}
+
+	if err := s.Delete(2, AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(2, AnyVersion); err != ErrUserNotFound {
+		t.Fatalf("second Delete(2): got %v, want %v", err, ErrUserNotFound)
+	}
+	if _, _, err := s.Create(User{ID: 1, Name: "joe"}); err != ErrUserExists {
+		t.Fatalf("Create with taken ID: got %v, want %v", err, ErrUserExists)
+	}
+	// ID 2 was deleted, but is not handed out again.
+	if usr, _, err := s.Create(User{Name: "joe"}); err != nil || usr.ID != 3 {
+		t.Fatalf("Create() = %v, %v; want ID 3", usr, err)
+	}
+}
+
+func TestMemUserStore(t *testing.T) {
+	testUserStore(t, NewMemUserStore())
+}
+
+func TestUserStoreLastID(t *testing.T) {
+	s := NewMemUserStore()
+	if _, err := s.Put(User{ID: math.MaxInt64, Name: "john"}, AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	if usr, _, err := s.Create(User{Name: "jane"}); err != ErrNoFreeID {
+		t.Fatalf("Create after ID %d = %v, %v; want %v", int64(math.MaxInt64), usr, err, ErrNoFreeID)
+	}
+}
+
+func TestFileUserStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.log")
+	s, err := OpenFileUserStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testUserStore(t, s)
+	s.Close()
+
+	s, err = OpenFileUserStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []User{{ID: 1, Name: "john", Age: 22}, {ID: 3, Name: "joe"}}; !reflect.DeepEqual(list, want) {
+		t.Fatalf("after reopen List() = %v, want %v", list, want)
+	}
+}
+
+func TestFileUserStorePartialRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.log")
+	s, err := OpenFileUserStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	john, _, err := s.Create(User{Name: "john"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"op":"put","id":2,"ver`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for i := 0; i < 2; i++ {
+		s, err = OpenFileUserStore(path)
+		if err != nil {
+			t.Fatalf("open %d with a partial record: %v", i+1, err)
+		}
+		list, err := s.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []User{john}; i == 0 && !reflect.DeepEqual(list, want) {
+			t.Fatalf("List() = %v, want %v", list, want)
+		}
+		if _, _, err := s.Create(User{Name: "jane"}); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,16 @@
+#!/bin/sh
+# fetch.sh replaces the assets in dist with those of version $1 of the
+# swagger-ui-dist package. Commit them so that every build embeds them.
+set -e
+
+version=${1:?usage: fetch.sh version}
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+curl -fsSL "https://registry.npmjs.org/swagger-ui-dist/-/swagger-ui-dist-$version.tgz" | tar -xz -C "$tmp"
+find dist -type f ! -name README.md -delete
+for file in LICENSE index.html index.css favicon-16x16.png favicon-32x32.png oauth2-redirect.html \
+	swagger-ui.css swagger-ui-bundle.js swagger-ui-standalone-preset.js; do
+	cp "$tmp/package/$file" dist/
+done
+echo "$version" > dist/VERSION
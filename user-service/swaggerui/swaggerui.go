@@ -0,0 +1,87 @@
+// Package swaggerui serves Swagger UI from assets embedded in the binary.
+package swaggerui
+
+//go:generate sh fetch.sh 5.18.2
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+//go:embed dist
+var dist embed.FS
+
+// ErrNoAssets is returned if the binary was built with dist emptied, as
+// by a failed go generate.
+var ErrNoAssets = errors.New("swaggerui: assets are missing from dist; run go generate ./swaggerui")
+
+// Config configures the UI.
+type Config struct {
+	// SpecURL is the URL of the API document the UI loads.
+	SpecURL string
+	// PersistAuthorization keeps the credentials entered in the UI, such
+	// as bearer tokens, in the local storage of the browser.
+	PersistAuthorization bool
+}
+
+var initializer = template.Must(template.New("swagger-initializer.js").Parse(`window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: {{.SpecURL}},
+    persistAuthorization: {{.PersistAuthorization}},
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  });
+};
+`))
+
+// Initializer returns the script starting the UI with c.
+func Initializer(c Config) ([]byte, error) {
+	specURL, err := json.Marshal(c.SpecURL)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	err = initializer.Execute(&b, struct {
+		SpecURL              string
+		PersistAuthorization bool
+	}{string(specURL), c.PersistAuthorization})
+	return b.Bytes(), err
+}
+
+// New returns a handler serving the UI configured by c at its root. Strip
+// the path it is served under with http.StripPrefix.
+func New(c Config) (http.Handler, error) {
+	assets, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.Stat(assets, "swagger-ui-bundle.js"); err != nil {
+		return nil, ErrNoAssets
+	}
+	script, err := Initializer(c)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(assets)))
+	mux.HandleFunc("/swagger-initializer.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		http.ServeContent(w, r, "swagger-initializer.js", time.Time{}, bytes.NewReader(script))
+	})
+	return mux, nil
+}
@@ -0,0 +1,34 @@
+package swaggerui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInitializer(t *testing.T) {
+	script, err := Initializer(Config{SpecURL: `/openapi.json?x="y"`, PersistAuthorization: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`url: "/openapi.json?x=\"y\"",`, "persistAuthorization: true,"} {
+		if !strings.Contains(string(script), want) {
+			t.Errorf("script lacks %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	h, err := New(Config{SpecURL: "/openapi.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/", "/swagger-ui-bundle.js", "/swagger-ui-standalone-preset.js", "/swagger-ui.css", "/swagger-initializer.js"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tangblue/goapi/restful"
+)
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+// Claims are the claims of the tokens issued by Auth.
+type Claims struct {
+	jwt.StandardClaims
+	// Type tells access tokens from refresh tokens.
+	Type  string   `json:"typ"`
+	Roles []string `json:"roles,omitempty"`
+	// Scope is the space separated scopes granted to the subject.
+	Scope string `json:"scope,omitempty"`
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (a *Auth) signToken(sub, typ string, roles []string, ttl time.Duration) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	key := a.keys.Current()
+	now := time.Now()
+	token := jwt.NewWithClaims(key.Method, Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   sub,
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+		Type:  typ,
+		Roles: roles,
+		Scope: scopesOf(roles),
+	})
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.sign)
+}
+
+// issueTokens returns a new access and refresh token for sub. The roles
+// of sub are looked up now, so a refresh picks up changed roles.
+func (a *Auth) issueTokens(sub string) (JWTToken, error) {
+	access, err := a.signToken(sub, tokenTypeAccess, a.credentials.Roles(sub), a.accessTTL)
+	if err != nil {
+		return JWTToken{}, err
+	}
+	refresh, err := a.signToken(sub, tokenTypeRefresh, nil, a.refreshTTL)
+	if err != nil {
+		return JWTToken{}, err
+	}
+	return JWTToken{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(a.accessTTL / time.Second),
+	}, nil
+}
+
+// parseToken verifies tokenString and returns its claims if it is an
+// unexpired, unrevoked token of type typ.
+func (a *Auth) parseToken(tokenString, typ string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := a.keys.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		// Never let the token choose the algorithm of a key.
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key.verify, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errInvalidToken
+	}
+	// jwt-go accepts tokens without exp; ours always have one.
+	if claims.ExpiresAt == 0 || claims.Id == "" || claims.Type != typ {
+		return nil, errInvalidToken
+	}
+	if a.revocations.IsRevoked(claims.Id) {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
+// bearerToken returns the token of the authorization header.
+func (a *Auth) bearerToken(req *restful.Request) (string, error) {
+	ah, err := req.GetParameter(a.hpAuthorization)
+	if err != nil {
+		return "", err
+	}
+	bt := strings.Fields(ah.(string))
+	if len(bt) != 2 || !strings.EqualFold(bt[0], "bearer") {
+		return "", errInvalidToken
+	}
+	return bt[1], nil
+}
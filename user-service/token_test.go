@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+)
+
+func TestTokens(t *testing.T) {
+	keys, err := NewKeySet(NewHMACKey([]byte("secret")), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := NewCredentials("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.Add("john", "password", RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	a := NewAuth(keys, credentials, nil, time.Minute, time.Hour, AuthLimits{})
+
+	tokens, err := a.issueTokens("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.parseToken(tokens.Token, tokenTypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "john" {
+		t.Fatalf("sub = %q, want %q", claims.Subject, "john")
+	}
+	if !claims.HasScope(ScopeUsersWrite) || claims.HasScope(ScopeAuditRead) {
+		t.Fatalf("scope = %q", claims.Scope)
+	}
+	if _, err := a.parseToken(tokens.Token, tokenTypeRefresh); err == nil {
+		t.Fatal("access token accepted as refresh token")
+	}
+	if _, err := a.parseToken(tokens.RefreshToken, tokenTypeRefresh); err != nil {
+		t.Fatal(err)
+	}
+
+	a.revocations.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0))
+	if _, err := a.parseToken(tokens.Token, tokenTypeAccess); err == nil {
+		t.Fatal("revoked token accepted")
+	}
+	if a.revocations.RevokeIfNew(claims.Id, time.Unix(claims.ExpiresAt, 0)) {
+		t.Fatal("RevokeIfNew of a revoked token reported it as new")
+	}
+
+	expired, err := a.signToken("john", tokenTypeAccess, nil, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.parseToken(expired, tokenTypeAccess); err == nil {
+		t.Fatal("expired token accepted")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := NewKeySet(NewHMACKey([]byte("secret")), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAuth(keys, nil, nil, time.Minute, time.Hour, AuthLimits{})
+
+	tokens := []string{}
+	for _, each := range []interface{}{rsaKey, ecKey, edKey} {
+		key, err := NewSigningKey(each)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := keys.Rotate(key); err != nil {
+			t.Fatal(err)
+		}
+		token, err := a.signToken("john", tokenTypeAccess, nil, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	for i, token := range tokens {
+		if _, err := a.parseToken(token, tokenTypeAccess); err != nil {
+			t.Errorf("token %d: %v", i, err)
+		}
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 3 || jwks.Keys[0].Alg != "EdDSA" {
+		t.Fatalf("JWKS() = %+v", jwks)
+	}
+
+	expired, err := NewKeySet(keys.Current(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.Rotate(NewHMACKey([]byte("secret")))
+	a.keys = expired
+	if _, err := a.parseToken(tokens[2], tokenTypeAccess); err == nil {
+		t.Fatal("token of a key out of its rotation window accepted")
+	}
+}
@@ -0,0 +1,141 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// FileExporter writes every span as a line of OTLP/JSON, an
+// ExportTraceServiceRequest like those of the OpenTelemetry Collector
+// file exporter.
+type FileExporter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	service string
+}
+
+// NewFileExporter returns an exporter writing to w the spans of the
+// service named service.
+func NewFileExporter(w io.Writer, service string) *FileExporter {
+	return &FileExporter{w: w, service: service}
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              Kind           `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            struct {
+		Message string `json:"message,omitempty"`
+		Code    int    `json:"code,omitempty"`
+	} `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func keyValue(key, value string) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+	kv.Value.StringValue = value
+	return kv
+}
+
+func (e *FileExporter) Export(s *Span) {
+	s.mu.Lock()
+	o := otlpSpan{
+		TraceID:           s.Context.TraceID.String(),
+		SpanID:            s.Context.SpanID.String(),
+		TraceState:        s.Context.State,
+		Name:              s.Name,
+		Kind:              s.Kind,
+		StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+	}
+	if s.Parent != (SpanID{}) {
+		o.ParentSpanID = s.Parent.String()
+	}
+	keys := make([]string, 0, len(s.Attributes))
+	for key := range s.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		o.Attributes = append(o.Attributes, keyValue(key, s.Attributes[key]))
+	}
+	if s.Error != "" {
+		o.Status.Message = s.Error
+		o.Status.Code = 2 // STATUS_CODE_ERROR
+	}
+	s.mu.Unlock()
+
+	var ss otlpScopeSpans
+	ss.Scope.Name = e.service
+	ss.Spans = []otlpSpan{o}
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = []otlpKeyValue{keyValue("service.name", e.service)}
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+
+	b, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{rs}})
+	if err != nil {
+		log.Printf("Export span: %v", err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, err := e.w.Write(append(b, '\n')); err != nil {
+		log.Printf("Export span: %v", err)
+	}
+}
+
+// Recorder is an in-process collector keeping the spans, for tests.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (r *Recorder) Export(s *Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, s)
+}
+
+// Spans returns the spans in the order they ended.
+func (r *Recorder) Spans() []*Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*Span(nil), r.spans...)
+}
@@ -0,0 +1,205 @@
+// Package trace records spans of requests and continues the traces of
+// the W3C Trace Context headers traceparent and tracestate.
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// FlagSampled is the trace flag asking that the trace be recorded.
+const FlagSampled = 0x01
+
+// SpanContext identifies a span across processes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+	// State is the vendor specific tracestate, passed on unchanged.
+	State string
+}
+
+// IsValid reports whether neither ID is all zeros.
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != TraceID{} && c.SpanID != SpanID{}
+}
+
+func (c SpanContext) Sampled() bool {
+	return c.Flags&FlagSampled != 0
+}
+
+// Traceparent returns c in the format of the traceparent header.
+func (c SpanContext) Traceparent() string {
+	return "00-" + c.TraceID.String() + "-" + c.SpanID.String() + "-" + hex.EncodeToString([]byte{c.Flags})
+}
+
+var errTraceparent = errors.New("trace: invalid traceparent")
+
+// ParseTraceparent parses a traceparent header. Versions after 00 are
+// parsed as far as version 00 defines them.
+func ParseTraceparent(s string) (SpanContext, error) {
+	var c SpanContext
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || (len(s) > 55 && (s[:2] == "00" || s[55] != '-')) {
+		return c, errTraceparent
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' || strings.ToLower(s) != s {
+		return c, errTraceparent
+	}
+	var version, flags [1]byte
+	if _, err := hex.Decode(version[:], []byte(s[:2])); err != nil || version[0] == 0xff {
+		return c, errTraceparent
+	}
+	if _, err := hex.Decode(c.TraceID[:], []byte(s[3:35])); err != nil {
+		return c, errTraceparent
+	}
+	if _, err := hex.Decode(c.SpanID[:], []byte(s[36:52])); err != nil {
+		return c, errTraceparent
+	}
+	if _, err := hex.Decode(flags[:], []byte(s[53:55])); err != nil {
+		return c, errTraceparent
+	}
+	c.Flags = flags[0]
+	if !c.IsValid() {
+		return SpanContext{}, errTraceparent
+	}
+	return c, nil
+}
+
+// Extract returns the span context of the traceparent and tracestate
+// headers of h, and whether there is a valid one.
+func Extract(h http.Header) (SpanContext, bool) {
+	c, err := ParseTraceparent(h.Get("traceparent"))
+	if err != nil {
+		return SpanContext{}, false
+	}
+	c.State = strings.Join(h["Tracestate"], ",")
+	return c, true
+}
+
+// Kind tells the role of a span in a trace.
+type Kind int
+
+const (
+	KindInternal Kind = iota + 1
+	KindServer
+)
+
+// Span is a timed operation of a trace. Its fields must not be changed
+// before End, other than by its methods.
+type Span struct {
+	tracer *Tracer
+
+	Name       string
+	Kind       Kind
+	Context    SpanContext
+	Parent     SpanID
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]string
+	// Error describes why the operation failed, if it did.
+	Error string
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// SetAttribute sets an attribute of s.
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Attributes[key] = value
+}
+
+// SetError marks s failed.
+func (s *Span) SetError(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Error = msg
+}
+
+// Child starts a span of the same trace as s, with s as its parent.
+func (s *Span) Child(name string) *Span {
+	return s.tracer.start(name, KindInternal, s.Context, s.Context.SpanID)
+}
+
+// End ends s and exports it if it is sampled. Only the first call counts.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+
+	if s.Context.Sampled() {
+		s.tracer.exporter.Export(s)
+	}
+}
+
+// Exporter receives the spans that ended.
+type Exporter interface {
+	Export(*Span)
+}
+
+// Tracer starts spans and exports them.
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start starts a span continuing the trace of the remote parent, or a new
+// sampled trace if parent is not valid.
+func (t *Tracer) Start(name string, kind Kind, parent SpanContext) *Span {
+	if !parent.IsValid() {
+		parent = SpanContext{TraceID: newTraceID(), Flags: FlagSampled}
+	}
+	return t.start(name, kind, parent, parent.SpanID)
+}
+
+func (t *Tracer) start(name string, kind Kind, c SpanContext, parent SpanID) *Span {
+	c.SpanID = newSpanID()
+	return &Span{
+		tracer:     t,
+		Name:       name,
+		Kind:       kind,
+		Context:    c,
+		Parent:     parent,
+		StartTime:  time.Now(),
+		Attributes: map[string]string{},
+	}
+}
+
+func newTraceID() (id TraceID) {
+	for id == (TraceID{}) {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	for id == (SpanID{}) {
+		rand.Read(id[:])
+	}
+	return id
+}
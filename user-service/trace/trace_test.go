@@ -0,0 +1,101 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	c, err := ParseTraceparent(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Sampled() || c.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || c.SpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("ParseTraceparent(%q) = %+v", valid, c)
+	}
+	if got := c.Traceparent(); got != valid {
+		t.Errorf("Traceparent() = %q, want %q", got, valid)
+	}
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future"); err != nil {
+		t.Errorf("future version: %v", err)
+	}
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(s); err == nil {
+			t.Errorf("ParseTraceparent(%q) succeeded", s)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	in := http.Header{}
+	in.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Add("tracestate", "congo=t61rcWkgMzE")
+	in.Add("tracestate", "rojo=00f067aa0ba902b7")
+	parent, ok := Extract(in)
+	if !ok {
+		t.Fatal("Extract found no context")
+	}
+
+	var r Recorder
+	server := NewTracer(&r).Start("GET /users", KindServer, parent)
+	child := server.Child("handler")
+	child.End()
+	child.End()
+	server.End()
+
+	spans := r.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[1].Context.TraceID != parent.TraceID || spans[1].Parent != parent.SpanID {
+		t.Errorf("server span does not continue the remote trace: %+v", spans[1].Context)
+	}
+	if spans[0].Parent != server.Context.SpanID {
+		t.Errorf("child parent = %s, want %s", spans[0].Parent, server.Context.SpanID)
+	}
+
+	if got := child.Context.State; got != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Errorf("tracestate = %q", got)
+	}
+}
+
+func TestNotSampled(t *testing.T) {
+	var r Recorder
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	NewTracer(&r).Start("GET /users", KindServer, parent).End()
+	if n := len(r.Spans()); n != 0 {
+		t.Errorf("exported %d spans of an unsampled trace", n)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	var b bytes.Buffer
+	span := NewTracer(NewFileExporter(&b, "user-service")).Start("GET /users", KindServer, SpanContext{})
+	span.SetAttribute("http.method", "GET")
+	span.SetError("boom")
+	span.End()
+
+	var r otlpRequest
+	if err := json.Unmarshal(b.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	s := r.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if s.TraceID != span.Context.TraceID.String() || s.ParentSpanID != "" || s.Kind != KindServer || s.Status.Code != 2 {
+		t.Errorf("span = %+v", s)
+	}
+	if len(s.Attributes) != 1 || s.Attributes[0].Value.StringValue != "GET" {
+		t.Errorf("attributes = %+v", s.Attributes)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/wiki/user-service/trace"
+)
+
+// attrSpan is the request attribute holding the current span.
+const attrSpan = "span"
+
+// Tracing continues the traces of the traceparent and tracestate headers
+// of the requests, or starts new ones.
+type Tracing struct {
+	tracer *trace.Tracer
+}
+
+func NewTracing(tracer *trace.Tracer) *Tracing {
+	return &Tracing{tracer: tracer}
+}
+
+// Filter is a container filter starting the server span of a request. It
+// must be the first filter so that traceFilter and traced record
+// the others in the trace.
+func (t *Tracing) Filter(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	parent, _ := trace.Extract(req.Request.Header)
+	route := selectedRoutePath(req)
+	if route == "" {
+		route = req.Request.URL.Path
+	}
+	span := t.tracer.Start(req.Request.Method+" "+route, trace.KindServer, parent)
+	defer span.End()
+
+	// Trace Context Level 2 tells the client the trace of the response.
+	resp.AddHeader("traceresponse", span.Context.Traceparent())
+	req.SetAttribute(attrSpan, span)
+	next(req, resp)
+
+	status := resp.StatusCode()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	span.SetAttribute("http.method", req.Request.Method)
+	span.SetAttribute("http.route", selectedRoutePath(req))
+	span.SetAttribute("http.target", req.Request.URL.RequestURI())
+	span.SetAttribute("http.status_code", strconv.Itoa(status))
+	if id := RequestIDOf(req); id != "" {
+		span.SetAttribute("http.request_id", id)
+	}
+	if claims := ClaimsOf(req); claims != nil {
+		span.SetAttribute("enduser.id", claims.Subject)
+	}
+	if status >= http.StatusInternalServerError {
+		span.SetError(http.StatusText(status))
+	}
+}
+
+// SpanOf returns the current span of req, or nil if it is not traced.
+func SpanOf(req *restful.Request) *trace.Span {
+	span, _ := req.Attribute(attrSpan).(*trace.Span)
+	return span
+}
+
+// traceFilter records f as a span named name, which holds the spans of
+// the filters and handler f passes the request on to.
+func traceFilter(name string, f restful.FilterFunction) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+		parent := SpanOf(req)
+		if parent == nil {
+			f(req, resp, next)
+			return
+		}
+		span := parent.Child(name)
+		defer span.End()
+
+		req.SetAttribute(attrSpan, span)
+		f(req, resp, next)
+		req.SetAttribute(attrSpan, parent)
+	}
+}
+
+// traced records the handler of the route as a span. Use it with
+// restful.RouteBuilder.Do after the other functions adding filters.
+func traced(b *restful.RouteBuilder) {
+	b.Filter(traceFilter("handler", func(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+		next(req, resp)
+	}))
+}
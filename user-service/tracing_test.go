@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tangblue/wiki/user-service/trace"
+)
+
+func TestTracingFilter(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	var r trace.Recorder
+	c := newContract(t)
+	c.container.Filter(NewTracing(trace.NewTracer(&r)).Filter)
+	tokens, err := c.auth.issueTokens("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := bearer(tokens.Token)
+	header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := c.call("POST", "/users", header, `{"name": "john", "age": 30}`, http.StatusCreated)
+	if got := rec.Header().Get("traceresponse"); !strings.HasPrefix(got, "00-"+traceID+"-") {
+		t.Errorf("traceresponse = %q, want the trace %s", got, traceID)
+	}
+
+	spans := map[string]*trace.Span{}
+	for _, s := range r.Spans() {
+		if s.Context.TraceID.String() != traceID {
+			t.Errorf("span %s has the trace %s, want %s", s.Name, s.Context.TraceID, traceID)
+		}
+		spans[s.Name] = s
+	}
+	server := spans["POST /users/"]
+	if server == nil || server.Parent.String() != "00f067aa0ba902b7" {
+		t.Fatalf("no server span continuing the incoming one in %v", spans)
+	}
+	if s := spans["JWTAuthenticate"]; s == nil || s.Parent != server.Context.SpanID {
+		t.Errorf("JWTAuthenticate span = %+v, want a child of the server span", s)
+	}
+	if s := spans["scopeFilter"]; s == nil || s.Parent != spans["JWTAuthenticate"].Context.SpanID {
+		t.Errorf("scopeFilter span = %+v, want a child of the JWTAuthenticate span", s)
+	}
+}
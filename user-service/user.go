@@ -0,0 +1,482 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restfulspec"
+	"github.com/tangblue/wiki/user-service/problem"
+	"github.com/tangblue/wiki/user-service/validate"
+)
+
+type UID int64
+type User struct {
+	ID   UID    `json:"id" description:"identifier of the user" default:"1"`
+	Name string `json:"name" description:"name of the user" default:"john" required:"true" maxLength:"64"`
+	Age  int    `json:"age" description:"age of the user" default:"21" minimum:"0" maximum:"150"`
+	// Owner is set from the caller on creation; only admins may change it.
+	Owner string `json:"owner,omitempty" description:"name of the account owning the user" maxLength:"64"`
+}
+
+// mayModify reports whether the caller may change or delete usr. Admins
+// may change any user, others only the users they own.
+func mayModify(claims *Claims, usr User) bool {
+	if claims == nil {
+		return false
+	}
+	return claims.IsAdmin() || usr.Owner == claims.Subject
+}
+
+// userTarget names a user in the audit log.
+func userTarget(id UID) string {
+	return "users/" + strconv.FormatInt(int64(id), 10)
+}
+
+type UserResource struct {
+	auth *Auth
+
+	ppUID         *restful.Parameter
+	hpIfMatch     *restful.Parameter
+	hpIfNoneMatch *restful.Parameter
+	qpLimit       *restful.Parameter
+	qpOffset      *restful.Parameter
+	qpName        *restful.Parameter
+	qpMinAge      *restful.Parameter
+	qpMaxAge      *restful.Parameter
+	qpSort        *restful.Parameter
+	users         UserStore
+	audit         *AuditLog
+}
+
+func NewUserResource(auth *Auth, users UserStore, audit *AuditLog) *UserResource {
+	return &UserResource{
+		auth: auth,
+
+		ppUID: restful.PathParameter("userID", "identifier of the user").
+			DataType(UID(0)).
+			Regex("\\d+").
+			ValueRange(UID(1), UID(math.MaxInt64)),
+		hpIfMatch: restful.HeaderParameter("If-Match", "ETag of the user to be changed").
+			Required(false),
+		hpIfNoneMatch: restful.HeaderParameter("If-None-Match", "ETag of the cached user").
+			Required(false),
+		qpLimit: restful.QueryParameter("limit", "maximum number of users to return").
+			DataType(0).
+			ValueRange(1, maxListLimit).
+			DefaultValue(strconv.Itoa(defaultListLimit)),
+		qpOffset: restful.QueryParameter("offset", "number of users to skip").
+			DataType(0).
+			ValueRange(0, math.MaxInt32).
+			DefaultValue("0"),
+		qpName: restful.QueryParameter("name", "prefix of the names of the users").
+			DataType(""),
+		qpMinAge: restful.QueryParameter("minAge", "minimum age of the users").
+			DataType(0).
+			ValueRange(0, math.MaxInt32),
+		qpMaxAge: restful.QueryParameter("maxAge", "maximum age of the users").
+			DataType(0).
+			ValueRange(0, math.MaxInt32),
+		qpSort: restful.QueryParameter("sort", "field to sort by; prefix with - to sort descending").
+			DataType("").
+			AllowableValues(sortValues()).
+			DefaultValue("id"),
+		users: users,
+		audit: audit,
+	}
+}
+
+func (u *UserResource) WebService(path string, tags []string) *restful.WebService {
+	tagUsers := func(b *restful.RouteBuilder) {
+		b.Metadata(restfulspec.KeyOpenAPITags, tags)
+	}
+
+	ws := new(restful.WebService)
+	ws.Path(path).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON, restful.MIME_XML)
+
+	ws.Route(ws.GET("/").Doc("get all users").
+		Handler(u.findAllUsers).
+		Operation("listUsers").
+		Do(problem.Declare).
+		Param(u.qpLimit).
+		Param(u.qpOffset).
+		Param(u.qpName).
+		Param(u.qpMinAge).
+		Param(u.qpMaxAge).
+		Param(u.qpSort).
+		Returns(http.StatusOK, "OK", []User{}).
+		Metadata(KeyResponseHeaders, ResponseHeaders{
+			http.StatusOK: {
+				"X-Total-Count": "number of users matching the query",
+				"Link":          "first, prev, next and last pages",
+			},
+		}).
+		Do(tagUsers, u.auth.basicAuth, u.auth.requireScopes(ScopeUsersRead), validated, traced))
+
+	createdHeaders := ResponseHeaders{
+		http.StatusCreated: {
+			"ETag":     "version of the user",
+			"Location": "URL of the user",
+		},
+	}
+
+	ws.Route(ws.POST("").Doc("create a user with a new ID").
+		Notes("The ID of the user in the body is ignored.").
+		Handler(u.createUser).
+		Do(problem.Declare).
+		Reads(User{}).
+		Returns(http.StatusUnprocessableEntity, "Invalid user", problem.Problem{}).
+		Returns(http.StatusCreated, "Created", User{}).
+		Metadata(KeyResponseHeaders, createdHeaders).
+		Do(tagUsers, u.auth.jwtAuth, u.auth.requireScopes(ScopeUsersWrite), validated, traced))
+
+	ws.Route(ws.PUT("").Doc("create a user with the given ID").
+		Handler(u.createUserWithID).
+		Do(problem.Declare).
+		Reads(User{}).
+		Returns(http.StatusUnprocessableEntity, "Invalid user", problem.Problem{}).
+		Returns(http.StatusConflict, "User ID is taken", problem.Problem{}).
+		Returns(http.StatusCreated, "Created", User{}).
+		Metadata(KeyResponseHeaders, createdHeaders).
+		Do(tagUsers, u.auth.jwtAuth, u.auth.requireScopes(ScopeUsersWrite), validated, traced))
+
+	ws.Route(ws.GET("/{%s}", u.ppUID).Doc("get a user").
+		Handler(u.findUser).
+		Operation("getUser").
+		Do(problem.Declare).
+		Param(u.hpIfNoneMatch).
+		Returns(http.StatusNotFound, "Not Found", problem.Problem{}).
+		Returns(http.StatusNotModified, "Not Modified", nil).
+		Returns(http.StatusOK, "OK", User{}).
+		Metadata(KeyResponseHeaders, ResponseHeaders{
+			http.StatusOK: {"ETag": "version of the user"},
+		}).
+		Do(tagUsers, validated, traced))
+
+	ws.Route(ws.PUT("/{%s}", u.ppUID).Doc("update a user").
+		Handler(u.updateUser).
+		Do(problem.Declare).
+		Reads(User{}).
+		Returns(http.StatusUnprocessableEntity, "Invalid user", problem.Problem{}).
+		Param(u.hpIfMatch).
+		Returns(http.StatusNotFound, "Not Found", problem.Problem{}).
+		Returns(http.StatusConflict, "Conflict", problem.Problem{}).
+		Returns(http.StatusPreconditionFailed, "Precondition Failed", problem.Problem{}).
+		Returns(http.StatusOK, "OK", User{}).
+		Metadata(KeyResponseHeaders, ResponseHeaders{
+			http.StatusOK: {"ETag": "version of the user"},
+		}).
+		Do(tagUsers, u.auth.jwtAuth, u.auth.requireScopes(ScopeUsersWrite), validated, traced))
+
+	ws.Route(ws.PATCH("/{%s}", u.ppUID).Doc("patch a user").
+		Notes("The body is a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) of the user.").
+		Handler(u.patchUser).
+		Do(problem.Declare).
+		Consumes(MIME_MERGE_PATCH, MIME_JSON_PATCH).
+		Param(u.hpIfMatch).
+		Returns(http.StatusNotFound, "Not Found", problem.Problem{}).
+		Returns(http.StatusConflict, "Conflict", problem.Problem{}).
+		Returns(http.StatusPreconditionFailed, "Precondition Failed", problem.Problem{}).
+		Returns(http.StatusUnsupportedMediaType, "Unsupported Media Type", problem.Problem{}).
+		Returns(http.StatusUnprocessableEntity, "Invalid patched user", problem.Problem{}).
+		Returns(http.StatusOK, "OK", User{}).
+		Metadata(KeyResponseHeaders, ResponseHeaders{
+			http.StatusOK: {"ETag": "version of the user"},
+		}).
+		Do(tagUsers, u.auth.jwtAuth, u.auth.requireScopes(ScopeUsersWrite), validated, traced))
+
+	ws.Route(ws.DELETE("/{%s}", u.ppUID).Doc("delete a user").
+		Handler(u.removeUser).
+		Operation("deleteUser").
+		Do(problem.Declare).
+		Param(u.hpIfMatch).
+		Returns(http.StatusNotFound, "Not Found", problem.Problem{}).
+		Returns(http.StatusConflict, "Conflict", problem.Problem{}).
+		Returns(http.StatusPreconditionFailed, "Precondition Failed", problem.Problem{}).
+		Returns(http.StatusNoContent, "No Content", nil).
+		Do(tagUsers, u.auth.jwtAuth, u.auth.requireScopes(ScopeUsersDelete), validated, traced))
+
+	return ws
+}
+
+func (u *UserResource) findAllUsers(req *restful.Request, resp *restful.Response) {
+	q, err := u.parseUserQuery(req)
+	if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+
+	list, err := u.users.List()
+	if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	page, total := q.apply(list)
+	resp.AddHeader("X-Total-Count", strconv.Itoa(total))
+	resp.AddHeader("Link", q.links(req.Request.URL, total))
+	resp.WriteEntity(page)
+}
+
+func (u *UserResource) getUID(req *restful.Request) (UID, error) {
+	param, err := req.GetParameter(u.ppUID)
+	return param.(UID), err
+}
+
+func (u *UserResource) findUser(req *restful.Request, resp *restful.Response) {
+	id, err := u.getUID(req)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusBadRequest, "User ID is invalid."))
+		return
+	}
+
+	usr, v, err := u.users.Get(id)
+	if err == ErrUserNotFound {
+		problem.Write(req, resp, problem.New(http.StatusNotFound, "User could not be found."))
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+
+	resp.AddHeader("ETag", formatETag(v))
+	if inm := req.Request.Header.Get("If-None-Match"); inm != "" && etagMatch(inm, v, true) {
+		resp.WriteHeader(http.StatusNotModified)
+		return
+	}
+	resp.WriteEntity(usr)
+}
+
+func (u *UserResource) updateUser(req *restful.Request, resp *restful.Response) {
+	id, err := u.getUID(req)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusBadRequest, "User ID is invalid."))
+		return
+	}
+
+	usr, v, err := u.users.Get(id)
+	if err == ErrUserNotFound {
+		problem.Write(req, resp, problem.New(http.StatusNotFound, "User could not be found."))
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+
+	claims := ClaimsOf(req)
+	if !mayModify(claims, usr) {
+		problem.Write(req, resp, problem.New(http.StatusForbidden, "Caller is not allowed to do this."))
+		return
+	}
+
+	im := req.Request.Header.Get("If-Match")
+	if im != "" && !etagMatch(im, v, false) {
+		problem.Write(req, resp, problem.New(http.StatusPreconditionFailed, "User has been modified."))
+		return
+	}
+
+	before := usr
+	if err := req.ReadEntity(&usr); err != nil {
+		problem.Write(req, resp, problem.Decode(err))
+		return
+	}
+
+	usr.ID = id
+	if !claims.IsAdmin() {
+		usr.Owner = before.Owner
+	}
+	// Store only if nobody changed the user since it was read above.
+	v, err = u.users.Put(usr, v)
+	if err == ErrVersionMismatch {
+		if im != "" {
+			problem.Write(req, resp, problem.New(http.StatusPreconditionFailed, "User has been modified."))
+		} else {
+			problem.Write(req, resp, problem.New(http.StatusConflict, "User was modified concurrently."))
+		}
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	u.audit.Record(req, "", AuditUserUpdate, userTarget(id), before, usr)
+	resp.AddHeader("ETag", formatETag(v))
+	resp.WriteEntity(usr)
+}
+
+func (u *UserResource) patchUser(req *restful.Request, resp *restful.Response) {
+	id, err := u.getUID(req)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusBadRequest, "User ID is invalid."))
+		return
+	}
+	patch, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		problem.Write(req, resp, problem.Decode(err))
+		return
+	}
+
+	usr, v, err := u.users.Get(id)
+	if err == ErrUserNotFound {
+		problem.Write(req, resp, problem.New(http.StatusNotFound, "User could not be found."))
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+
+	claims := ClaimsOf(req)
+	if !mayModify(claims, usr) {
+		problem.Write(req, resp, problem.New(http.StatusForbidden, "Caller is not allowed to do this."))
+		return
+	}
+	im := req.Request.Header.Get("If-Match")
+	if im != "" && !etagMatch(im, v, false) {
+		problem.Write(req, resp, problem.New(http.StatusPreconditionFailed, "User has been modified."))
+		return
+	}
+
+	patched, err := applyPatch(usr, req.Request.Header.Get("Content-Type"), patch)
+	switch err {
+	case nil:
+	case errUnsupportedPatch:
+		problem.Write(req, resp, problem.New(http.StatusUnsupportedMediaType, "Patch media type is not supported."))
+		return
+	case errPatchTestFailed:
+		problem.Write(req, resp, problem.New(http.StatusConflict, "Patch test operation failed."))
+		return
+	default:
+		problem.Write(req, resp, problem.New(http.StatusBadRequest, "Patch is invalid: "+err.Error()))
+		return
+	}
+
+	if patched.ID != id {
+		problem.Write(req, resp, problem.New(http.StatusUnprocessableEntity, "User ID cannot be changed."))
+		return
+	}
+	if patched.Owner != usr.Owner && !claims.IsAdmin() {
+		problem.Write(req, resp, problem.New(http.StatusForbidden, "Caller is not allowed to do this."))
+		return
+	}
+	// The patched user is not read by ReadEntity, so check it here.
+	if err := validate.Struct(patched); err != nil {
+		problem.Write(req, resp, problem.Decode(err))
+		return
+	}
+
+	v, err = u.users.Put(patched, v)
+	if err == ErrVersionMismatch {
+		if im != "" {
+			problem.Write(req, resp, problem.New(http.StatusPreconditionFailed, "User has been modified."))
+		} else {
+			problem.Write(req, resp, problem.New(http.StatusConflict, "User was modified concurrently."))
+		}
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	u.audit.Record(req, "", AuditUserUpdate, userTarget(id), usr, patched)
+	resp.AddHeader("ETag", formatETag(v))
+	resp.WriteEntity(patched)
+}
+
+func (u *UserResource) createUser(req *restful.Request, resp *restful.Response) {
+	u.create(req, resp, true)
+}
+
+func (u *UserResource) createUserWithID(req *restful.Request, resp *restful.Response) {
+	u.create(req, resp, false)
+}
+
+// create stores the user of the request body. The store allocates the ID
+// if newID is set; otherwise the ID of the body must be free.
+func (u *UserResource) create(req *restful.Request, resp *restful.Response, newID bool) {
+	usr := User{}
+	if err := req.ReadEntity(&usr); err != nil {
+		problem.Write(req, resp, problem.Decode(err))
+		return
+	}
+	if newID {
+		usr.ID = 0
+	} else if usr.ID <= 0 {
+		problem.Write(req, resp, problem.New(http.StatusBadRequest, "User ID is invalid."))
+		return
+	}
+
+	claims := ClaimsOf(req)
+	if usr.Owner == "" || !claims.IsAdmin() {
+		usr.Owner = claims.Subject
+	}
+
+	usr, v, err := u.users.Create(usr)
+	if err == ErrUserExists {
+		problem.Write(req, resp, problem.New(http.StatusConflict, "User ID is taken."))
+		return
+	} else if err == ErrNoFreeID {
+		problem.Write(req, resp, problem.New(http.StatusConflict, "No user ID is left."))
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	u.audit.Record(req, "", AuditUserCreate, userTarget(usr.ID), nil, usr)
+	location := strings.TrimSuffix(req.Request.URL.Path, "/") + "/" + strconv.FormatInt(int64(usr.ID), 10)
+	resp.AddHeader("Location", location)
+	resp.AddHeader("ETag", formatETag(v))
+	resp.WriteHeaderAndEntity(http.StatusCreated, usr)
+}
+
+func (u *UserResource) removeUser(req *restful.Request, resp *restful.Response) {
+	id, err := u.getUID(req)
+	if err != nil {
+		problem.Write(req, resp, problem.New(http.StatusBadRequest, "User ID is invalid."))
+		return
+	}
+
+	im := req.Request.Header.Get("If-Match")
+	usr, v, err := u.users.Get(id)
+	if err == ErrUserNotFound {
+		if im != "" {
+			problem.Write(req, resp, problem.New(http.StatusPreconditionFailed, "User has been modified."))
+		} else {
+			problem.Write(req, resp, problem.New(http.StatusNotFound, "User could not be found."))
+		}
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+
+	if !mayModify(ClaimsOf(req), usr) {
+		problem.Write(req, resp, problem.New(http.StatusForbidden, "Caller is not allowed to do this."))
+		return
+	}
+	if im != "" && !etagMatch(im, v, false) {
+		problem.Write(req, resp, problem.New(http.StatusPreconditionFailed, "User has been modified."))
+		return
+	}
+
+	// Delete only if nobody changed the owner since it was checked above.
+	err = u.users.Delete(id, v)
+	if err == ErrVersionMismatch {
+		if im != "" {
+			problem.Write(req, resp, problem.New(http.StatusPreconditionFailed, "User has been modified."))
+		} else {
+			problem.Write(req, resp, problem.New(http.StatusConflict, "User was modified concurrently."))
+		}
+		return
+	} else if err == ErrUserNotFound {
+		// Deleted concurrently.
+		problem.Write(req, resp, problem.New(http.StatusNotFound, "User could not be found."))
+		return
+	} else if err != nil {
+		problem.WriteError(req, resp, err)
+		return
+	}
+	u.audit.Record(req, "", AuditUserDelete, userTarget(id), usr, nil)
+	resp.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/wiki/user-service/problem"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// userLess orders users by the field named in the sort query parameter.
+var userLess = map[string]func(a, b User) bool{
+	"id":    func(a, b User) bool { return a.ID < b.ID },
+	"name":  func(a, b User) bool { return a.Name < b.Name },
+	"age":   func(a, b User) bool { return a.Age < b.Age },
+	"owner": func(a, b User) bool { return a.Owner < b.Owner },
+}
+
+func sortValues() map[string]string {
+	values := map[string]string{}
+	for field := range userLess {
+		values[field] = "ascending " + field
+		values["-"+field] = "descending " + field
+	}
+	return values
+}
+
+// userQuery selects, orders and pages the users of findAllUsers.
+type userQuery struct {
+	limit  int
+	offset int
+	name   string
+	minAge *int
+	maxAge *int
+	sort   string
+}
+
+// optionalParameter returns the value of a query parameter, or nil if the
+// request does not have it.
+func optionalParameter(req *restful.Request, p *restful.Parameter) (interface{}, error) {
+	name := p.Data().Name
+	if req.Request.URL.Query().Get(name) == "" {
+		return nil, nil
+	}
+	v, err := req.GetParameter(p)
+	if err != nil {
+		return nil, problem.New(http.StatusBadRequest, "Query parameter is invalid.").
+			WithField(name, err.Error())
+	}
+	return v, nil
+}
+
+func (u *UserResource) parseUserQuery(req *restful.Request) (userQuery, error) {
+	q := userQuery{limit: defaultListLimit, sort: "id"}
+	for _, each := range []struct {
+		p   *restful.Parameter
+		set func(v interface{})
+	}{
+		{u.qpLimit, func(v interface{}) { q.limit = v.(int) }},
+		{u.qpOffset, func(v interface{}) { q.offset = v.(int) }},
+		{u.qpName, func(v interface{}) { q.name = v.(string) }},
+		{u.qpMinAge, func(v interface{}) { age := v.(int); q.minAge = &age }},
+		{u.qpMaxAge, func(v interface{}) { age := v.(int); q.maxAge = &age }},
+		{u.qpSort, func(v interface{}) { q.sort = v.(string) }},
+	} {
+		v, err := optionalParameter(req, each.p)
+		if err != nil {
+			return q, err
+		} else if v != nil {
+			each.set(v)
+		}
+	}
+	if _, ok := userLess[strings.TrimPrefix(q.sort, "-")]; !ok {
+		return q, problem.New(http.StatusBadRequest, "Query parameter is invalid.").
+			WithField(u.qpSort.Data().Name, "unknown field "+strings.TrimPrefix(q.sort, "-"))
+	}
+	return q, nil
+}
+
+// apply returns the requested page of list and the number of users
+// matching the query.
+func (q userQuery) apply(list []User) ([]User, int) {
+	matched := []User{}
+	for _, each := range list {
+		if !strings.HasPrefix(each.Name, q.name) ||
+			(q.minAge != nil && each.Age < *q.minAge) ||
+			(q.maxAge != nil && each.Age > *q.maxAge) {
+			continue
+		}
+		matched = append(matched, each)
+	}
+
+	// The store lists users by ID, so a stable sort breaks ties by ID.
+	less := userLess[strings.TrimPrefix(q.sort, "-")]
+	if strings.HasPrefix(q.sort, "-") {
+		sort.SliceStable(matched, func(i, j int) bool { return less(matched[j], matched[i]) })
+	} else {
+		sort.SliceStable(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+	}
+
+	total := len(matched)
+	if q.offset >= total {
+		return []User{}, total
+	}
+	end := q.offset + q.limit
+	if end > total {
+		end = total
+	}
+	return matched[q.offset:end], total
+}
+
+// links returns the RFC 8288 Link header paging through the result.
+func (q userQuery) links(u *url.URL, total int) string {
+	link := func(rel string, offset int) string {
+		v := u.Query()
+		v.Set("offset", strconv.Itoa(offset))
+		v.Set("limit", strconv.Itoa(q.limit))
+		l := *u
+		l.RawQuery = v.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, l.RequestURI(), rel)
+	}
+
+	last := 0
+	if total > 0 {
+		last = (total - 1) / q.limit * q.limit
+	}
+	links := []string{link("first", 0)}
+	if q.offset > 0 {
+		prev := q.offset - q.limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, link("prev", prev))
+	}
+	if q.offset+q.limit < total {
+		links = append(links, link("next", q.offset+q.limit))
+	}
+	links = append(links, link("last", last))
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestUserQuery(t *testing.T) {
+	list := []User{
+		{ID: 1, Name: "john", Age: 30},
+		{ID: 2, Name: "jane", Age: 25},
+		{ID: 3, Name: "joe", Age: 30},
+		{ID: 4, Name: "bob", Age: 40},
+	}
+	age := 26
+
+	for _, tt := range []struct {
+		q     userQuery
+		want  []UID
+		total int
+	}{
+		{userQuery{limit: 10, sort: "id"}, []UID{1, 2, 3, 4}, 4},
+		{userQuery{limit: 10, sort: "-age"}, []UID{4, 1, 3, 2}, 4},
+		{userQuery{limit: 10, sort: "name", name: "j"}, []UID{2, 3, 1}, 3},
+		{userQuery{limit: 10, sort: "id", minAge: &age}, []UID{1, 3, 4}, 3},
+		{userQuery{limit: 10, sort: "id", maxAge: &age}, []UID{2}, 1},
+		{userQuery{limit: 2, offset: 1, sort: "id"}, []UID{2, 3}, 4},
+		{userQuery{limit: 2, offset: 4, sort: "id"}, []UID{}, 4},
+	} {
+		page, total := tt.q.apply(list)
+		ids := []UID{}
+		for _, each := range page {
+			ids = append(ids, each.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) || total != tt.total {
+			t.Errorf("%+v: got %v of %d, want %v of %d", tt.q, ids, total, tt.want, tt.total)
+		}
+	}
+}
+
+func TestUserQueryLinks(t *testing.T) {
+	u, _ := url.Parse("/users/?name=j&offset=2&limit=2")
+	q := userQuery{limit: 2, offset: 2, name: "j", sort: "id"}
+	want := `</users/?limit=2&name=j&offset=0>; rel="first", ` +
+		`</users/?limit=2&name=j&offset=0>; rel="prev", ` +
+		`</users/?limit=2&name=j&offset=4>; rel="next", ` +
+		`</users/?limit=2&name=j&offset=4>; rel="last"`
+	if got := q.links(u, 5); got != want {
+		t.Errorf("links() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tangblue/goapi/restful"
+)
+
+func TestUserOwnership(t *testing.T) {
+	keys, err := NewKeySet(NewHMACKey([]byte("secret")), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := NewCredentials("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.Add("john", "password", RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuth(keys, credentials, NewAuditLog(), time.Minute, time.Hour, AuthLimits{})
+	tokens, err := auth.issueTokens("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := NewMemUserStore()
+	registerEntityAccessors()
+	container := restful.NewContainer()
+	container.Add(NewUserResource(auth, users, NewAuditLog()).WebService("/users", []string{"users"}))
+
+	call := func(method string, id UID, contentType, body string) int {
+		req := httptest.NewRequest(method, "/users/"+strconv.FormatInt(int64(id), 10), strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokens.Token)
+		req.Header.Set("Accept", restful.MIME_JSON)
+		if body != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, c := range []struct {
+		owner string
+		want  [3]int
+	}{
+		{"jane", [3]int{http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+		{"john", [3]int{http.StatusOK, http.StatusOK, http.StatusNoContent}},
+	} {
+		usr, _, err := users.Create(User{Name: "user", Age: 20, Owner: c.owner})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := [3]int{
+			call("PUT", usr.ID, restful.MIME_JSON, `{"name": "user", "age": 21}`),
+			call("PATCH", usr.ID, MIME_MERGE_PATCH, `{"age": 22}`),
+			call("DELETE", usr.ID, "", ""),
+		}
+		if got != c.want {
+			t.Errorf("john on the user of %s: PUT, PATCH, DELETE = %v, want %v", c.owner, got, c.want)
+		}
+		if _, _, err := users.Get(usr.ID); (err == nil) != (c.owner != "john") {
+			t.Errorf("user of %s: Get after DELETE = %v", c.owner, err)
+		}
+	}
+}
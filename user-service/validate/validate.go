@@ -0,0 +1,270 @@
+// Package validate checks structs against constraints declared in their
+// field tags, and documents the same constraints in OpenAPI schemas.
+//
+// The tags are those restfulspec already reads, plus a few of our own:
+//
+//	required:"true"   the field must not be the zero value
+//	minimum:"0"       numbers must not be less
+//	maximum:"150"     numbers must not be greater
+//	enum:"a|b|c"      the field must be one of the values
+//	minLength:"1"     strings, slices and maps must not be shorter
+//	maxLength:"64"    strings, slices and maps must not be longer
+//	pattern:"^\w+$"   strings must match the regular expression
+//
+// A field holding its zero value is taken as missing, so that only
+// required applies to it.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/spec"
+)
+
+// FieldError is a constraint violated by a field.
+type FieldError struct {
+	Field  string
+	Detail string
+}
+
+// Errors are the constraints violated by a struct.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, each := range e {
+		msgs[i] = each.Field + " " + each.Detail
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Struct checks the fields of the struct v, or the struct v points to,
+// and those of its nested structs. It returns Errors if any constraint is
+// violated. Fields are named as in JSON, nested ones joined by dots.
+func Struct(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs Errors
+	checkStruct(rv, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func checkStruct(rv reflect.Value, prefix string, errs *Errors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := jsonName(field)
+		if field.PkgPath != "" || name == "" {
+			continue
+		}
+		name = prefix + name
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				if field.Tag.Get("required") == "true" {
+					*errs = append(*errs, FieldError{name, "is required"})
+				}
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if detail := checkField(field.Tag, fv); detail != "" {
+			*errs = append(*errs, FieldError{name, detail})
+		} else if fv.Kind() == reflect.Struct {
+			checkStruct(fv, name+".", errs)
+		}
+	}
+}
+
+// checkField returns what is wrong with the value fv of a field with tag,
+// or "" if nothing is. The zero value of a required field stands for a
+// missing one, and so does that of an omitempty field, which encoding/json
+// leaves out. Other zero values are checked like any.
+func checkField(tag reflect.StructTag, fv reflect.Value) string {
+	if fv.IsZero() {
+		if tag.Get("required") == "true" {
+			return "is required"
+		}
+		if strings.Contains(tag.Get("json"), ",omitempty") {
+			return ""
+		}
+	}
+
+	switch fv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n := fv.Len()
+		if fv.Kind() == reflect.String {
+			n = len([]rune(fv.String()))
+		}
+		if min, ok := intTag(tag, "minLength"); ok && n < min {
+			return fmt.Sprintf("must be at least %d long", min)
+		}
+		if max, ok := intTag(tag, "maxLength"); ok && n > max {
+			return fmt.Sprintf("must be at most %d long", max)
+		}
+	}
+	if re := patternTag(tag); re != nil && fv.Kind() == reflect.String && !re.MatchString(fv.String()) {
+		return "must match " + re.String()
+	}
+
+	if number, ok := numberOf(fv); ok {
+		if min, ok := floatTag(tag, "minimum"); ok && number < min {
+			return "must be at least " + tag.Get("minimum")
+		}
+		if max, ok := floatTag(tag, "maximum"); ok && number > max {
+			return "must be at most " + tag.Get("maximum")
+		}
+	}
+
+	if values := tag.Get("enum"); values != "" {
+		s := fmt.Sprint(fv.Interface())
+		for _, each := range strings.Split(values, "|") {
+			if each == s {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Replace(values, "|", ", ", -1)
+	}
+	return ""
+}
+
+// Schema adds the constraints of the fields of the struct type of model
+// to its schema s. The properties restfulspec built from the json tags
+// become required only if their fields are.
+func Schema(s *spec.Schema, model interface{}) {
+	rt := reflect.TypeOf(model)
+	for rt.Kind() == reflect.Ptr || rt.Kind() == reflect.Slice {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return
+	}
+
+	required := []string{}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := jsonName(field)
+		prop, ok := s.Properties[name]
+		if field.PkgPath != "" || name == "" || !ok {
+			continue
+		}
+		if field.Tag.Get("required") == "true" {
+			required = append(required, name)
+		}
+		if min, ok := intTag(field.Tag, "minLength"); ok {
+			n := int64(min)
+			if prop.Type.Contains("array") {
+				prop.MinItems = &n
+			} else {
+				prop.MinLength = &n
+			}
+		}
+		if max, ok := intTag(field.Tag, "maxLength"); ok {
+			n := int64(max)
+			if prop.Type.Contains("array") {
+				prop.MaxItems = &n
+			} else {
+				prop.MaxLength = &n
+			}
+		}
+		if re := patternTag(field.Tag); re != nil {
+			prop.Pattern = re.String()
+		}
+		s.Properties[name] = prop
+	}
+	s.Required = required
+}
+
+// EntityAccessor wraps erw, so that the entities it reads are checked with
+// Struct. Register it for the MIME types of the container:
+//
+//	restful.RegisterEntityAccessor(restful.MIME_JSON,
+//		validate.EntityAccessor(restful.NewEntityAccessorJSON(restful.MIME_JSON)))
+func EntityAccessor(erw restful.EntityReaderWriter) restful.EntityReaderWriter {
+	return entityAccessor{erw}
+}
+
+type entityAccessor struct {
+	restful.EntityReaderWriter
+}
+
+func (a entityAccessor) Read(req *restful.Request, v interface{}) error {
+	if err := a.EntityReaderWriter.Read(req, v); err != nil {
+		return err
+	}
+	return Struct(v)
+}
+
+func numberOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	} else if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func intTag(tag reflect.StructTag, key string) (int, bool) {
+	s := tag.Get(key)
+	if s == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic(fmt.Sprintf("validate: %s tag %q is not an integer", key, s))
+	}
+	return n, true
+}
+
+func floatTag(tag reflect.StructTag, key string) (float64, bool) {
+	s := tag.Get(key)
+	if s == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validate: %s tag %q is not a number", key, s))
+	}
+	return f, true
+}
+
+// patterns caches the compiled pattern tags.
+var patterns sync.Map
+
+func patternTag(tag reflect.StructTag) *regexp.Regexp {
+	s := tag.Get("pattern")
+	if s == "" {
+		return nil
+	}
+	if re, ok := patterns.Load(s); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(s)
+	patterns.Store(s, re)
+	return re
+}
@@ -0,0 +1,86 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+type address struct {
+	City string `json:"city" required:"true"`
+}
+
+type person struct {
+	Name    string   `json:"name" required:"true" maxLength:"5"`
+	Age     int      `json:"age" minimum:"0" maximum:"150"`
+	Adult   int      `json:"adult" minimum:"18"`
+	Level   string   `json:"level" enum:"low|high"`
+	Code    string   `json:"code,omitempty" pattern:"^[A-Z]{2}$"`
+	Color   string   `json:"color,omitempty" enum:"red|green"`
+	Tags    []string `json:"tags" minLength:"1"`
+	Home    *address `json:"home,omitempty"`
+	private int      `minimum:"1"`
+}
+
+func TestStruct(t *testing.T) {
+	valid := person{Name: "ann", Age: 30, Adult: 18, Level: "low", Code: "DE", Tags: []string{"a"}, Home: &address{City: "Bonn"}}
+	if err := Struct(&valid); err != nil {
+		t.Errorf("Struct(%+v) = %v", valid, err)
+	}
+	zero := valid
+	zero.Age, zero.Code, zero.Color = 0, "", ""
+	if err := Struct(&zero); err != nil {
+		t.Errorf("Struct(%+v) = %v; zero values that satisfy the constraints or are left out are valid", zero, err)
+	}
+
+	for _, tt := range []struct {
+		change func(p *person)
+		field  string
+	}{
+		{func(p *person) { p.Name = "" }, "name"},
+		{func(p *person) { p.Name = "annabel" }, "name"},
+		{func(p *person) { p.Age = -5 }, "age"},
+		{func(p *person) { p.Age = 151 }, "age"},
+		{func(p *person) { p.Adult = 0 }, "adult"},
+		{func(p *person) { p.Level = "" }, "level"},
+		{func(p *person) { p.Code = "de" }, "code"},
+		{func(p *person) { p.Color = "blue" }, "color"},
+		{func(p *person) { p.Tags = []string{} }, "tags"},
+		{func(p *person) { p.Home = &address{} }, "home.city"},
+	} {
+		p := valid
+		tt.change(&p)
+		err := Struct(p)
+		errs, ok := err.(Errors)
+		if !ok || len(errs) != 1 || errs[0].Field != tt.field {
+			t.Errorf("Struct(%+v) = %v, want an error of %s", p, err, tt.field)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	s := spec.Schema{SchemaProps: spec.SchemaProps{
+		Required: []string{"name", "age", "tags"},
+		Properties: map[string]spec.Schema{
+			"name": *spec.StringProperty(),
+			"age":  *spec.Int32Property(),
+			"code": *spec.StringProperty(),
+			"tags": *spec.ArrayProperty(spec.StringProperty()),
+		},
+	}}
+	Schema(&s, person{})
+
+	if !reflect.DeepEqual(s.Required, []string{"name"}) {
+		t.Errorf("Required = %v, want [name]", s.Required)
+	}
+	if p := s.Properties["name"]; p.MaxLength == nil || *p.MaxLength != 5 {
+		t.Errorf("name.MaxLength = %v, want 5", p.MaxLength)
+	}
+	if p := s.Properties["code"]; p.Pattern != "^[A-Z]{2}$" {
+		t.Errorf("code.Pattern = %q", p.Pattern)
+	}
+	if p := s.Properties["tags"]; p.MinItems == nil || *p.MinItems != 1 {
+		t.Errorf("tags.MinItems = %v, want 1", p.MinItems)
+	}
+}